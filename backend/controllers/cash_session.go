@@ -0,0 +1,343 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"main/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// validCashMovementTypes are the ways cash enters or leaves a drawer outside of sales
+var validCashMovementTypes = map[string]bool{
+	"cash_in":  true,
+	"cash_out": true,
+}
+
+// CashSummary is the cash a drawer should hold
+type CashSummary struct {
+	OpeningFloat models.Money  `json:"opening_float"`
+	CashSales    models.Money  `json:"cash_sales"` // Cash payments taken, change already given back
+	CashIn       models.Money  `json:"cash_in"`
+	CashOut      models.Money  `json:"cash_out"`
+	CashRefunds  models.Money  `json:"cash_refunds"`
+	Expected     models.Money  `json:"expected"` // OpeningFloat + CashSales + CashIn - CashOut - CashRefunds
+	Counted      *models.Money `json:"counted"`
+	Difference   *models.Money `json:"difference"` // Counted - Expected
+}
+
+// ZReport summarises a cash session: the invoices paid through it and their
+// tax, and the payments, refunds and cash taken in or paid from its drawer.
+// An invoice paid through several sessions counts in each for the share of it
+// paid there, so the sessions' reports add up to the invoice.
+// Sessions run side by side, so nothing is selected by time alone. Orders
+// have no discounts yet, so there is no discount section.
+type ZReport struct {
+	Session  models.CashSession `json:"session"`
+	From     time.Time          `json:"from"`
+	To       time.Time          `json:"to"`
+	Invoices ZReportInvoices    `json:"invoices"`
+	Payments []ZReportMethod    `json:"payments"` // By method, reversed payments excluded
+	Rounding models.Money       `json:"rounding"` // Cash rounding written off, included in Payments
+	Refunds  ZReportRefunds     `json:"refunds"`
+	Taxes    []ZReportTax       `json:"taxes"`
+	Cash     CashSummary        `json:"cash"`
+}
+
+// ZReportInvoices are the invoices with a payment taken in the session that
+// is not reversed. The amounts are the session's share of each invoice.
+type ZReportInvoices struct {
+	Count    int64        `json:"count"`
+	Subtotal models.Money `json:"subtotal"`
+	Tax      models.Money `json:"tax"`
+	Total    models.Money `json:"total"`
+	Voided   int64        `json:"voided"` // Invoices with a session payment that were voided, not included above
+}
+
+type ZReportMethod struct {
+	Method string       `json:"method"`
+	Count  int64        `json:"count"`
+	Amount models.Money `json:"amount"`
+}
+
+type ZReportRefunds struct {
+	Count    int64           `json:"count"`
+	Total    models.Money    `json:"total"`    // Positive amount paid back
+	Rounding models.Money    `json:"rounding"` // Cash rounding on cash refunds, positive when less was paid back; not in Total
+	ByMethod []ZReportMethod `json:"by_method"`
+}
+
+type ZReportTax struct {
+	Code      string       `json:"code"`
+	Name      string       `json:"name"`
+	Rate      float64      `json:"rate"`
+	Inclusive bool         `json:"inclusive"`
+	Collected models.Money `json:"collected"` // Session's share of the tax on invoices paid through it
+	Refunded  models.Money `json:"refunded"`  // On credit notes paid from the session, positive
+	Net       models.Money `json:"net"`
+}
+
+// findCashSession resolves the drawer session a payment or refund belongs to:
+// the given session, which must be open, else the open session of the staff
+// member, else none. The session row is share locked, so it cannot be closed
+// until the payment or refund is committed.
+func findCashSession(tx *gorm.DB, sessionID *uint, staff string) (*uint, error) {
+	var session models.CashSession
+	locked := tx.Clauses(clause.Locking{Strength: "SHARE"})
+	if sessionID != nil {
+		if err := locked.First(&session, *sessionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, badRequest("Cash session %d not found", *sessionID)
+			}
+			return nil, err
+		}
+		if session.Status != "open" {
+			return nil, conflict("Cash session %d is closed", session.ID)
+		}
+		return &session.ID, nil
+	}
+
+	if staff == "" {
+		return nil, nil
+	}
+	err := locked.Where("cashier = ? AND status = ?", staff, "open").First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session.ID, nil
+}
+
+// lockCashSession loads a cash session and locks its row until the transaction ends
+func lockCashSession(tx *gorm.DB, sessionID uint) (models.CashSession, error) {
+	var session models.CashSession
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, sessionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return session, notFound("Cash session not found")
+	}
+	return session, err
+}
+
+// sessionCash works out the cash a session's drawer should hold
+func sessionCash(tx *gorm.DB, session models.CashSession) (CashSummary, error) {
+	summary := CashSummary{
+		OpeningFloat: session.OpeningFloat,
+		Counted:      session.CountedCash,
+	}
+
+	if err := tx.Model(&models.Payment{}).
+		Where("cash_session_id = ? AND method = ? AND reversed = ?", session.ID, "cash", false).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&summary.CashSales).Error; err != nil {
+		return summary, err
+	}
+
+	var movements []models.CashMovement
+	if err := tx.Where("cash_session_id = ?", session.ID).Find(&movements).Error; err != nil {
+		return summary, err
+	}
+	for _, movement := range movements {
+		if movement.Type == "cash_in" {
+			summary.CashIn += movement.Amount
+		} else {
+			summary.CashOut += movement.Amount
+		}
+	}
+
+	var refunded models.Money
+	if err := tx.Model(&models.CreditNote{}).
+		Where("cash_session_id = ? AND refund_method = ?", session.ID, "cash").
+		Select("COALESCE(SUM(total_amount - cash_rounding), 0)").
+		Scan(&refunded).Error; err != nil {
+		return summary, err
+	}
+	summary.CashRefunds = -refunded
+
+	summary.Expected = summary.OpeningFloat + summary.CashSales + summary.CashIn - summary.CashOut - summary.CashRefunds
+	if summary.Counted != nil {
+		difference := *summary.Counted - summary.Expected
+		summary.Difference = &difference
+	}
+	return summary, nil
+}
+
+// buildZReport summarises a session; for an open session it is an interim
+// report up to now
+func buildZReport(tx *gorm.DB, session models.CashSession) (ZReport, error) {
+	report := ZReport{
+		Session: session,
+		From:    session.OpenedAt,
+		To:      time.Now(),
+	}
+	if session.ClosedAt != nil {
+		report.To = *session.ClosedAt
+	}
+
+	shares, err := sessionInvoiceShares(tx, session.ID)
+	if err != nil {
+		return report, err
+	}
+	for _, share := range shares {
+		report.Invoices.Count++
+		report.Invoices.Subtotal += share.of(share.SubtotalAmount)
+		report.Invoices.Tax += share.of(share.TaxAmount)
+		report.Invoices.Total += share.of(share.TotalAmount)
+	}
+	// Voiding reverses the payments, so voided invoices are found by any payment
+	if err := tx.Model(&models.Invoice{}).
+		Where("id IN (?)", tx.Model(&models.Payment{}).Select("invoice_id").Where("cash_session_id = ?", session.ID)).
+		Where("voided_at IS NOT NULL").
+		Count(&report.Invoices.Voided).Error; err != nil {
+		return report, err
+	}
+
+	if err := tx.Model(&models.Payment{}).
+		Select("method, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+		Where("cash_session_id = ? AND reversed = ?", session.ID, false).
+		Group("method").Order("method").
+		Scan(&report.Payments).Error; err != nil {
+		return report, err
+	}
+	for _, method := range report.Payments {
+		if method.Method == "cash_rounding" {
+			report.Rounding = method.Amount
+		}
+	}
+
+	if err := tx.Model(&models.CreditNote{}).
+		Select("refund_method AS method, COUNT(*) AS count, COALESCE(-SUM(total_amount - cash_rounding), 0) AS amount").
+		Where("cash_session_id = ?", session.ID).
+		Group("refund_method").Order("refund_method").
+		Scan(&report.Refunds.ByMethod).Error; err != nil {
+		return report, err
+	}
+	for _, method := range report.Refunds.ByMethod {
+		report.Refunds.Count += method.Count
+		report.Refunds.Total += method.Amount
+	}
+	if err := tx.Model(&models.CreditNote{}).
+		Where("cash_session_id = ?", session.ID).
+		Select("COALESCE(-SUM(cash_rounding), 0)").
+		Scan(&report.Refunds.Rounding).Error; err != nil {
+		return report, err
+	}
+
+	taxes, err := sessionTaxes(tx, session, shares)
+	if err != nil {
+		return report, err
+	}
+	report.Taxes = taxes
+
+	report.Cash, err = sessionCash(tx, session)
+	return report, err
+}
+
+// invoiceShare is the part of an invoice paid through one session
+type invoiceShare struct {
+	InvoiceID      uint
+	SubtotalAmount models.Money
+	TaxAmount      models.Money
+	TotalAmount    models.Money
+	SessionPaid    models.Money // Payments taken in the session
+	Paid           models.Money // All payments
+}
+
+// of returns the session's share of an amount of the invoice. Shares are taken
+// of what the invoice was paid, or of its total while it is not fully paid, so
+// the sessions' shares of an invoice add up to at most the whole.
+func (s invoiceShare) of(amount models.Money) models.Money {
+	whole := max(s.Paid, s.TotalAmount)
+	if whole <= 0 {
+		return 0
+	}
+	return amount.Prorate(int64(s.SessionPaid), int64(whole))
+}
+
+// sessionInvoiceShares finds the invoices, not voided, with a payment taken in
+// a session that is not reversed, with what was paid there and overall
+func sessionInvoiceShares(tx *gorm.DB, sessionID uint) ([]invoiceShare, error) {
+	var shares []invoiceShare
+	err := tx.Table("invoices").
+		Select(`invoices.id AS invoice_id, invoices.subtotal_amount, invoices.tax_amount, invoices.total_amount,
+			COALESCE(SUM(payments.amount) FILTER (WHERE payments.cash_session_id = ?), 0) AS session_paid,
+			COALESCE(SUM(payments.amount), 0) AS paid`, sessionID).
+		Joins("JOIN payments ON payments.invoice_id = invoices.id AND NOT payments.reversed AND payments.deleted_at IS NULL").
+		Where("invoices.voided_at IS NULL AND invoices.deleted_at IS NULL").
+		Where("invoices.id IN (?)", tx.Model(&models.Payment{}).
+			Select("invoice_id").
+			Where("cash_session_id = ? AND reversed = ?", sessionID, false)).
+		Group("invoices.id").
+		Order("invoices.id").
+		Scan(&shares).Error
+	return shares, err
+}
+
+// sessionTaxes lists the session's share of the tax per rate on the invoices
+// paid through it, and the tax given back on the credit notes paid from it
+func sessionTaxes(tx *gorm.DB, session models.CashSession, shares []invoiceShare) ([]ZReportTax, error) {
+	type taxRow struct {
+		Code      string
+		Name      string
+		Rate      float64
+		Inclusive bool
+		Amount    models.Money
+	}
+
+	sharesByInvoice := map[uint]invoiceShare{}
+	invoiceIDs := make([]uint, 0, len(shares))
+	for _, share := range shares {
+		sharesByInvoice[share.InvoiceID] = share
+		invoiceIDs = append(invoiceIDs, share.InvoiceID)
+	}
+	var invoiceTaxes []models.InvoiceTax
+	if err := tx.Where("invoice_id IN ?", invoiceIDs).Order("id").Find(&invoiceTaxes).Error; err != nil {
+		return nil, err
+	}
+	collected := make([]taxRow, 0, len(invoiceTaxes))
+	for _, invoiceTax := range invoiceTaxes {
+		collected = append(collected, taxRow{
+			Code:      invoiceTax.Code,
+			Name:      invoiceTax.Name,
+			Rate:      invoiceTax.Rate,
+			Inclusive: invoiceTax.Inclusive,
+			Amount:    sharesByInvoice[invoiceTax.InvoiceID].of(invoiceTax.TaxAmount),
+		})
+	}
+
+	var refunded []taxRow
+	if err := tx.Table("credit_note_taxes").
+		Select("credit_note_taxes.code, credit_note_taxes.name, credit_note_taxes.rate, credit_note_taxes.inclusive, -SUM(credit_note_taxes.tax_amount) AS amount").
+		Joins("JOIN credit_notes ON credit_notes.id = credit_note_taxes.credit_note_id").
+		Where("credit_notes.cash_session_id = ? AND credit_notes.deleted_at IS NULL", session.ID).
+		Group("credit_note_taxes.code, credit_note_taxes.name, credit_note_taxes.rate, credit_note_taxes.inclusive").
+		Scan(&refunded).Error; err != nil {
+		return nil, err
+	}
+
+	taxes := []ZReportTax{}
+	index := map[taxRow]int{}
+	row := func(r taxRow) *ZReportTax {
+		key := taxRow{Code: r.Code, Name: r.Name, Rate: r.Rate, Inclusive: r.Inclusive}
+		i, ok := index[key]
+		if !ok {
+			i = len(taxes)
+			index[key] = i
+			taxes = append(taxes, ZReportTax{Code: r.Code, Name: r.Name, Rate: r.Rate, Inclusive: r.Inclusive})
+		}
+		return &taxes[i]
+	}
+	for _, r := range collected {
+		row(r).Collected += r.Amount
+	}
+	for _, r := range refunded {
+		row(r).Refunded += r.Amount
+	}
+	for i := range taxes {
+		taxes[i].Net = taxes[i].Collected - taxes[i].Refunded
+	}
+	return taxes, nil
+}
@@ -0,0 +1,393 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/models"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type OpenCashSessionRequest struct {
+	Cashier      string       `json:"cashier" binding:"required"`
+	Drawer       string       `json:"drawer"`
+	OpeningFloat models.Money `json:"opening_float"`
+	Notes        string       `json:"notes"`
+}
+
+type CashMovementRequest struct {
+	Type      string       `json:"type" binding:"required"` // cash_in, cash_out
+	Amount    models.Money `json:"amount" binding:"required"`
+	Reason    string       `json:"reason" binding:"required"`
+	CreatedBy string       `json:"created_by"`
+}
+
+type CloseCashSessionRequest struct {
+	CountedCash *models.Money `json:"counted_cash" binding:"required"`
+	ClosedBy    string        `json:"closed_by"`
+	Notes       string        `json:"notes"`
+}
+
+func OpenCashSession(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /api/cash-sessions called")
+
+	var req OpenCashSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	session := models.CashSession{
+		Cashier:      strings.TrimSpace(req.Cashier),
+		Drawer:       strings.TrimSpace(req.Drawer),
+		Status:       "open",
+		OpenedAt:     time.Now(),
+		OpeningFloat: req.OpeningFloat,
+		Notes:        req.Notes,
+	}
+	if session.Cashier == "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Cashier is required",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+	if session.OpeningFloat < 0 {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Opening float cannot be negative",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var open int64
+		if err := tx.Model(&models.CashSession{}).Where("cashier = ? AND status = ?", session.Cashier, "open").Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return conflict("%s already has an open cash session", session.Cashier)
+		}
+		return tx.Create(&session).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to open cash session")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Cash session opened successfully",
+		Data:    session,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func GetCashSessions(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/cash-sessions called")
+
+	query := db.Model(&models.CashSession{})
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if cashier := r.URL.Query().Get("cashier"); cashier != "" {
+		query = query.Where("cashier = ?", cashier)
+	}
+
+	var sessions []models.CashSession
+	if err := query.Order("opened_at DESC").Limit(100).Find(&sessions).Error; err != nil {
+		log.Printf("Error fetching cash sessions: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve cash sessions",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Cash sessions retrieved successfully",
+		Data:    sessions,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetCashSessionByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/cash-sessions/%s called", id)
+
+	sessionID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid cash session ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var session models.CashSession
+	if err := db.Preload("Movements").First(&session, uint(sessionID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Cash session not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching cash session: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve cash session",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Cash session retrieved successfully",
+		Data:    session,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func AddCashMovement(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/cash-sessions/%s/movements called", id)
+
+	sessionID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid cash session ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req CashMovementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	movement := models.CashMovement{
+		CashSessionID: uint(sessionID),
+		Type:          strings.ToLower(req.Type),
+		Amount:        req.Amount,
+		Reason:        strings.TrimSpace(req.Reason),
+		CreatedBy:     strings.TrimSpace(req.CreatedBy),
+	}
+	var message string
+	switch {
+	case !validCashMovementTypes[movement.Type]:
+		message = "Invalid movement type. Must be one of: cash_in, cash_out"
+	case movement.Amount <= 0:
+		message = "Amount must be greater than 0"
+	case movement.Reason == "":
+		message = "Reason is required"
+	}
+	if message != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: message,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		session, err := lockCashSession(tx, uint(sessionID))
+		if err != nil {
+			return err
+		}
+		if session.Status != "open" {
+			return conflict("Cash session %d is closed", session.ID)
+		}
+		return tx.Create(&movement).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to record cash movement")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Cash movement recorded successfully",
+		Data:    movement,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func CloseCashSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/cash-sessions/%s/close called", id)
+
+	sessionID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid cash session ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req CloseCashSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if req.CountedCash == nil || *req.CountedCash < 0 {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Counted cash is required and cannot be negative",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var report ZReport
+	err = db.Transaction(func(tx *gorm.DB) error {
+		session, err := lockCashSession(tx, uint(sessionID))
+		if err != nil {
+			return err
+		}
+		if session.Status != "open" {
+			return conflict("Cash session %d is already closed", session.ID)
+		}
+
+		cash, err := sessionCash(tx, session)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		difference := *req.CountedCash - cash.Expected
+		session.Status = "closed"
+		session.ClosedAt = &now
+		session.ClosedBy = strings.TrimSpace(req.ClosedBy)
+		session.ExpectedCash = &cash.Expected
+		session.CountedCash = req.CountedCash
+		session.Difference = &difference
+		if req.Notes != "" {
+			session.Notes = strings.TrimSpace(session.Notes + "\n" + req.Notes)
+		}
+		if err := tx.Save(&session).Error; err != nil {
+			return err
+		}
+
+		report, err = buildZReport(tx, session)
+		return err
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to close cash session")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Cash session closed successfully",
+		Data:    report,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetZReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/cash-sessions/%s/z-report called", id)
+
+	sessionID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid cash session ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var session models.CashSession
+	if err := db.Preload("Movements").First(&session, uint(sessionID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Cash session not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching cash session: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve cash session",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	report, err := buildZReport(db, session)
+	if err != nil {
+		log.Printf("Error building Z report: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to build Z report",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Z report generated successfully",
+		Data:    report,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"testing"
+
+	"main/models"
+)
+
+func TestInvoiceShare(t *testing.T) {
+	tests := []struct {
+		name        string
+		total       string
+		sessionPaid string
+		paid        string
+		amount      string
+		want        string
+	}{
+		{"paid in one session", "115.00", "115.00", "115.00", "15.00", "15.00"},
+		{"split over two sessions", "115.00", "46.00", "115.00", "15.00", "6.00"},
+		{"the other session", "115.00", "69.00", "115.00", "15.00", "9.00"},
+		{"partly paid", "100.00", "25.00", "25.00", "100.00", "25.00"},
+		{"overpaid", "100.00", "120.00", "120.00", "100.00", "100.00"},
+		{"overpaid over two sessions", "100.00", "60.00", "120.00", "100.00", "50.00"},
+		{"free invoice", "0.00", "0.00", "0.00", "0.00", "0.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			share := invoiceShare{
+				TotalAmount: money(t, tt.total),
+				SessionPaid: money(t, tt.sessionPaid),
+				Paid:        money(t, tt.paid),
+			}
+			if got := share.of(money(t, tt.amount)); got != money(t, tt.want) {
+				t.Errorf("share of %s = %s, want %s", tt.amount, got, tt.want)
+			}
+		})
+	}
+
+	// Two sessions splitting an invoice report it once between them
+	first := invoiceShare{TotalAmount: 1000, SessionPaid: 333, Paid: 1000}
+	second := invoiceShare{TotalAmount: 1000, SessionPaid: 667, Paid: 1000}
+	var tax models.Money = 150
+	if sum := first.of(tax) + second.of(tax); sum != tax {
+		t.Errorf("shares of %s add up to %s", tax, sum)
+	}
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"main/models"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type CreateCreditNoteRequest struct {
+	Lines        []RefundLineRequest `json:"lines"`                            // Empty refunds everything not refunded yet
+	RefundMethod string              `json:"refund_method" binding:"required"` // cash, card, bank_transfer, voucher
+	Reason       string              `json:"reason" binding:"required"`
+	CreatedBy    string              `json:"created_by"`
+
+	CashSessionID *uint `json:"cash_session_id"` // Drawer a cash refund is paid from, defaults to created_by's open session
+}
+
+type RefundLineRequest struct {
+	OrderItemID uint `json:"order_item_id" binding:"required"`
+	Quantity    int  `json:"quantity" binding:"required"`
+}
+
+func CreateCreditNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/invoices/%s/credit-notes called", id)
+
+	invoiceID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid invoice ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req CreateCreditNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var creditNote models.CreditNote
+	err = db.Transaction(func(tx *gorm.DB) error {
+		invoice, err := lockInvoice(tx, "id = ?", uint(invoiceID))
+		if err != nil {
+			return err
+		}
+		if invoice == nil {
+			return notFound("Invoice not found")
+		}
+		creditNote, err = issueCreditNote(tx, invoice, req)
+		return err
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to create credit note")
+		return
+	}
+
+	var createdCreditNote models.CreditNote
+	if err := preloadCreditNote(db).First(&createdCreditNote, creditNote.ID).Error; err != nil {
+		log.Printf("Error loading created credit note: %v", err)
+		createdCreditNote = creditNote
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Credit note created successfully",
+		Data:    createdCreditNote,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func GetInvoiceCreditNotes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/invoices/%s/credit-notes called", id)
+
+	invoiceID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid invoice ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var creditNotes []models.CreditNote
+	if err := preloadCreditNote(db).Where("invoice_id = ?", uint(invoiceID)).Order("id").Find(&creditNotes).Error; err != nil {
+		log.Printf("Error fetching credit notes: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve credit notes",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Credit notes retrieved successfully",
+		Data:    creditNotes,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetCreditNoteByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/credit-notes/%s called", id)
+
+	creditNoteID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid credit note ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var creditNote models.CreditNote
+	if err := preloadCreditNote(db).First(&creditNote, uint(creditNoteID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Credit note not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching credit note: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve credit note",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Credit note retrieved successfully",
+		Data:    creditNote,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func preloadCreditNote(query *gorm.DB) *gorm.DB {
+	return query.Preload("Lines").Preload("Taxes")
+}
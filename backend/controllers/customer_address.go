@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+
+	"main/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// validateAddress trims an address and returns what is wrong with it, if anything
+func validateAddress(address *models.CustomerAddress) string {
+	for _, field := range []*string{&address.Label, &address.Line1, &address.Line2, &address.City, &address.Landmark, &address.DeliveryNotes} {
+		*field = strings.TrimSpace(*field)
+	}
+
+	if address.Line1 == "" {
+		return "Address line1 is required"
+	}
+	if (address.Latitude == nil) != (address.Longitude == nil) {
+		return "Latitude and longitude must be given together"
+	}
+	if address.Latitude != nil && (*address.Latitude < -90 || *address.Latitude > 90) {
+		return "Latitude must be between -90 and 90"
+	}
+	if address.Longitude != nil && (*address.Longitude < -180 || *address.Longitude > 180) {
+		return "Longitude must be between -180 and 180"
+	}
+	return ""
+}
+
+// findCustomerAddress loads one of a customer's addresses and locks it
+func findCustomerAddress(tx *gorm.DB, customerID, addressID uint) (models.CustomerAddress, error) {
+	var address models.CustomerAddress
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND customer_id = ?", addressID, customerID).
+		First(&address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return address, notFound("Address %d not found for customer %d", addressID, customerID)
+	}
+	return address, err
+}
+
+// setDefaultAddress makes an address the customer's only default one
+func setDefaultAddress(tx *gorm.DB, address *models.CustomerAddress) error {
+	if err := tx.Model(&models.CustomerAddress{}).
+		Where("customer_id = ? AND id <> ? AND is_default = ?", address.CustomerID, address.ID, true).
+		Update("is_default", false).Error; err != nil {
+		return err
+	}
+	address.IsDefault = true
+	return tx.Model(address).Update("is_default", true).Error
+}
+
+// applyDeliveryAddress copies a saved address of the customer onto the order.
+// The copy keeps old orders and invoices unchanged when the address is
+// edited later.
+func applyDeliveryAddress(tx *gorm.DB, order *models.Order, addressID uint, notes string) error {
+	var address models.CustomerAddress
+	if err := tx.Where("id = ? AND customer_id = ?", addressID, order.CustomerID).First(&address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return badRequest("Delivery address %d not found for customer %d", addressID, order.CustomerID)
+		}
+		return err
+	}
+
+	order.DeliveryAddressID = &address.ID
+	order.DeliveryAddress = address.Format()
+	order.DeliveryLatitude = address.Latitude
+	order.DeliveryLongitude = address.Longitude
+	order.DeliveryNotes = address.DeliveryNotes
+	if notes = strings.TrimSpace(notes); notes != "" {
+		order.DeliveryNotes = notes
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"main/models"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// CustomerAddressRequest creates an address, or changes the fields that are present
+type CustomerAddressRequest struct {
+	Label         *string  `json:"label"`
+	Line1         *string  `json:"line1"`
+	Line2         *string  `json:"line2"`
+	City          *string  `json:"city"`
+	Landmark      *string  `json:"landmark"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	DeliveryNotes *string  `json:"delivery_notes"`
+	IsDefault     *bool    `json:"is_default"`
+}
+
+// apply copies the fields that are present onto the address
+func (req CustomerAddressRequest) apply(address *models.CustomerAddress) {
+	for _, field := range []struct {
+		value *string
+		to    *string
+	}{
+		{req.Label, &address.Label},
+		{req.Line1, &address.Line1},
+		{req.Line2, &address.Line2},
+		{req.City, &address.City},
+		{req.Landmark, &address.Landmark},
+		{req.DeliveryNotes, &address.DeliveryNotes},
+	} {
+		if field.value != nil {
+			*field.to = *field.value
+		}
+	}
+	if req.Latitude != nil {
+		address.Latitude = req.Latitude
+	}
+	if req.Longitude != nil {
+		address.Longitude = req.Longitude
+	}
+}
+
+// parseAddressIDs reads the customer and address IDs from the URL
+func parseAddressIDs(r *http.Request) (uint, uint, error) {
+	vars := mux.Vars(r)
+	customerID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		return 0, 0, badRequest("Invalid customer ID")
+	}
+	addressID, err := strconv.ParseUint(vars["addressId"], 10, 32)
+	if err != nil {
+		return 0, 0, badRequest("Invalid address ID")
+	}
+	return uint(customerID), uint(addressID), nil
+}
+
+func GetCustomerAddresses(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/customers/%s/addresses called", id)
+
+	customerID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid ID",
+		})
+		return
+	}
+
+	if _, err := findCustomer(db, uint(customerID)); err != nil {
+		sendRequestError(w, err, "Failed to retrieve addresses")
+		return
+	}
+
+	var addresses []models.CustomerAddress
+	if err := db.Where("customer_id = ?", uint(customerID)).Order("is_default DESC, id").Find(&addresses).Error; err != nil {
+		sendRequestError(w, err, "Failed to retrieve addresses")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Addresses retrieved successfully",
+		Data:    addresses,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// CreateCustomerAddress saves a new address. A customer's first address
+// becomes the default one.
+func CreateCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/customers/%s/addresses called", id)
+
+	customerID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid ID",
+		})
+		return
+	}
+
+	var req CustomerAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	address := models.CustomerAddress{CustomerID: uint(customerID)}
+	req.apply(&address)
+	if msg := validateAddress(&address); msg != "" {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: msg,
+		})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if _, err := findCustomer(tx, address.CustomerID); err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Model(&models.CustomerAddress{}).Where("customer_id = ?", address.CustomerID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&address).Error; err != nil {
+			return err
+		}
+		if existing == 0 || (req.IsDefault != nil && *req.IsDefault) {
+			return setDefaultAddress(tx, &address)
+		}
+		return nil
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to create address")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Address created successfully",
+		Data:    address,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func UpdateCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	log.Printf("PUT /api/customers/%s/addresses/%s called", mux.Vars(r)["id"], mux.Vars(r)["addressId"])
+
+	customerID, addressID, err := parseAddressIDs(r)
+	if err != nil {
+		sendRequestError(w, err, "Failed to update address")
+		return
+	}
+
+	var req CustomerAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	var address models.CustomerAddress
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if address, err = findCustomerAddress(tx, customerID, addressID); err != nil {
+			return err
+		}
+
+		req.apply(&address)
+		if msg := validateAddress(&address); msg != "" {
+			return badRequest("%s", msg)
+		}
+		if err := tx.Model(&address).
+			Select("label", "line1", "line2", "city", "landmark", "latitude", "longitude", "delivery_notes").
+			Updates(&address).Error; err != nil {
+			return err
+		}
+
+		if req.IsDefault != nil && *req.IsDefault {
+			return setDefaultAddress(tx, &address)
+		}
+		if req.IsDefault != nil && address.IsDefault {
+			address.IsDefault = false
+			return tx.Model(&address).Update("is_default", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to update address")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Address updated successfully",
+		Data:    address,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// DeleteCustomerAddress soft deletes an address. When it was the default, the
+// customer's oldest remaining address takes over. Orders keep their copy.
+func DeleteCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	log.Printf("DELETE /api/customers/%s/addresses/%s called", mux.Vars(r)["id"], mux.Vars(r)["addressId"])
+
+	customerID, addressID, err := parseAddressIDs(r)
+	if err != nil {
+		sendRequestError(w, err, "Failed to delete address")
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		address, err := findCustomerAddress(tx, customerID, addressID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&address).Update("is_default", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&address).Error; err != nil {
+			return err
+		}
+		if !address.IsDefault {
+			return nil
+		}
+
+		var next models.CustomerAddress
+		err = tx.Where("customer_id = ?", customerID).Order("id").First(&next).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return setDefaultAddress(tx, &next)
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to delete address")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Address deleted successfully",
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func SetDefaultCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	log.Printf("POST /api/customers/%s/addresses/%s/default called", mux.Vars(r)["id"], mux.Vars(r)["addressId"])
+
+	customerID, addressID, err := parseAddressIDs(r)
+	if err != nil {
+		sendRequestError(w, err, "Failed to set default address")
+		return
+	}
+
+	var address models.CustomerAddress
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if address, err = findCustomerAddress(tx, customerID, addressID); err != nil {
+			return err
+		}
+		return setDefaultAddress(tx, &address)
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to set default address")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Default address set successfully",
+		Data:    address,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
@@ -2,16 +2,80 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"net/mail"
 	"strconv"
+	"strings"
 
 	"main/models"
 	"main/utils"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// UpdateCustomerRequest changes only the fields that are present
+type UpdateCustomerRequest struct {
+	Name  *string `json:"name"`
+	TelNo *string `json:"tel_no"`
+	Email *string `json:"email"`
+	Notes *string `json:"notes"`
+}
+
+// validateCustomer trims a customer's fields and returns what is wrong with
+// them, if anything. The phone number is only normalised when checkPhone is
+// set, so customers stored before numbers were normalised can still be edited.
+func validateCustomer(customer *models.Customer, checkPhone bool) string {
+	customer.Name = strings.TrimSpace(customer.Name)
+	customer.TelNo = strings.TrimSpace(customer.TelNo)
+	customer.Email = strings.TrimSpace(customer.Email)
+	customer.Notes = strings.TrimSpace(customer.Notes)
+
+	if customer.Name == "" || customer.TelNo == "" {
+		return "Name and TelNo cannot be empty"
+	}
+	if checkPhone {
+		tel, err := utils.NormalizePhone(customer.TelNo)
+		if err != nil {
+			return "Invalid phone number " + customer.TelNo
+		}
+		customer.TelE164 = &tel
+	}
+	if customer.Email != "" {
+		if address, err := mail.ParseAddress(customer.Email); err != nil || address.Address != customer.Email {
+			return "Invalid email address"
+		}
+	}
+	return ""
+}
+
+// checkPhoneAvailable fails when a customer other than customerID already has the number
+func checkPhoneAvailable(tx *gorm.DB, tel string, customerID uint) error {
+	var existing models.Customer
+	err := tx.Where("tel_e164 = ? AND id <> ?", tel, customerID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return conflict("Phone number %s already belongs to customer %d (%s)", tel, existing.ID, existing.Name)
+}
+
+// DuplicateCustomers are live customers whose phone numbers normalise to the same number
+type DuplicateCustomers struct {
+	TelE164   string            `json:"tel_e164"`
+	Customers []models.Customer `json:"customers"`
+}
+
+type CustomerDuplicatesReport struct {
+	Duplicates   []DuplicateCustomers `json:"duplicates"`
+	InvalidPhone []models.Customer    `json:"invalid_phone"`
+}
+
 func GetCustomers(w http.ResponseWriter, r *http.Request) {
 	log.Println("GET /api/customers called")
 
@@ -75,20 +139,22 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if customer.Name == "" || customer.TelNo == "" {
+	if msg := validateCustomer(&customer, true); msg != "" {
 		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
 			Success: false,
-			Message: "Name and TelNo cannot be empty",
+			Message: msg,
 		})
 		return
 	}
 
-	result := db.Create(&customer)
-	if result.Error != nil {
-		utils.SendJSONResponse(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: result.Error.Error(),
-		})
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := checkPhoneAvailable(tx, *customer.TelE164, 0); err != nil {
+			return err
+		}
+		return tx.Create(&customer).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to create customer")
 		return
 	}
 
@@ -105,21 +171,112 @@ func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 	log.Printf("PUT /api/customers/%s called", id)
 
-	// TODO: Parse request body and update customer
+	customerID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid ID",
+		})
+		return
+	}
+
+	var req UpdateCustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	var customer models.Customer
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&customer, uint(customerID)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return notFound("Customer not found")
+			}
+			return err
+		}
+
+		if req.Name != nil {
+			customer.Name = *req.Name
+		}
+		if req.TelNo != nil {
+			customer.TelNo = *req.TelNo
+		}
+		if req.Email != nil {
+			customer.Email = *req.Email
+		}
+		if req.Notes != nil {
+			customer.Notes = *req.Notes
+		}
+		if msg := validateCustomer(&customer, req.TelNo != nil); msg != "" {
+			return badRequest("%s", msg)
+		}
+		if req.TelNo == nil {
+			return tx.Model(&customer).Select("name", "email", "notes").Updates(&customer).Error
+		}
+		if err := checkPhoneAvailable(tx, *customer.TelE164, customer.ID); err != nil {
+			return err
+		}
+
+		return tx.Model(&customer).Select("name", "tel_no", "tel_e164", "email", "notes").Updates(&customer).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to update customer")
+		return
+	}
+
 	response := utils.APIResponse{
 		Success: true,
 		Message: "Customer updated successfully",
-		Data:    nil, // Placeholder
+		Data:    customer,
 	}
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
 
+// DeleteCustomer soft deletes a customer. Customers with orders that are not
+// delivered or cancelled yet are kept.
 func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 	log.Printf("DELETE /api/customers/%s called", id)
 
-	// TODO: Implement soft delete for customer
+	customerID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid ID",
+		})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var customer models.Customer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&customer, uint(customerID)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return notFound("Customer not found")
+			}
+			return err
+		}
+
+		var openOrders int64
+		if err := tx.Model(&models.Order{}).
+			Where("customer_id = ? AND order_status NOT IN ?", customer.ID, finalOrderStatuses).
+			Count(&openOrders).Error; err != nil {
+			return err
+		}
+		if openOrders > 0 {
+			return conflict("Customer has %d open order(s) and cannot be deleted", openOrders)
+		}
+
+		return tx.Delete(&customer).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to delete customer")
+		return
+	}
+
 	response := utils.APIResponse{
 		Success: true,
 		Message: "Customer deleted successfully",
@@ -127,11 +284,85 @@ func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
 
+func GetDeletedCustomers(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/customers/deleted called")
+
+	var customers []models.Customer
+	result := db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&customers)
+	if result.Error != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, utils.APIResponse{
+			Success: false,
+			Message: result.Error.Error(),
+		})
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Deleted customers retrieved successfully",
+		Data:    customers,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func RestoreCustomer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/customers/%s/restore called", id)
+
+	customerID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid ID",
+		})
+		return
+	}
+
+	var customer models.Customer
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&customer, uint(customerID)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return notFound("Customer not found")
+			}
+			return err
+		}
+		if !customer.DeletedAt.Valid {
+			return conflict("Customer is not deleted")
+		}
+		if customer.TelE164 != nil {
+			if err := checkPhoneAvailable(tx, *customer.TelE164, customer.ID); err != nil {
+				return err
+			}
+		}
+
+		customer.DeletedAt = gorm.DeletedAt{}
+		return tx.Unscoped().Model(&customer).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to restore customer")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Customer restored successfully",
+		Data:    customer,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// GetCustomerByTelNo finds a customer by phone number in any common format
 func GetCustomerByTelNo(w http.ResponseWriter, r *http.Request) {
 	telNo := mux.Vars(r)["telno"]
 
+	query := db.Where("tel_no = ?", telNo)
+	if tel, err := utils.NormalizePhone(telNo); err == nil {
+		query = db.Where("tel_e164 = ? OR tel_no = ?", tel, telNo)
+	}
+
 	var customer models.Customer
-	result := db.Where("tel_no = ?", telNo).First(&customer)
+	result := query.Order("id").First(&customer)
 	if result.Error != nil {
 		utils.SendJSONResponse(w, http.StatusNotFound, utils.APIResponse{
 			Success: false,
@@ -147,3 +378,54 @@ func GetCustomerByTelNo(w http.ResponseWriter, r *http.Request) {
 	}
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
+
+// GetCustomerDuplicates lists live customers sharing a phone number once
+// normalised, and customers whose number cannot be normalised
+func GetCustomerDuplicates(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/customers/duplicates called")
+
+	var customers []models.Customer
+	result := db.Order("id").Find(&customers)
+	if result.Error != nil {
+		utils.SendJSONResponse(w, http.StatusInternalServerError, utils.APIResponse{
+			Success: false,
+			Message: result.Error.Error(),
+		})
+		return
+	}
+
+	report := CustomerDuplicatesReport{
+		Duplicates:   []DuplicateCustomers{},
+		InvalidPhone: []models.Customer{},
+	}
+	groups := map[string]int{}
+	for _, customer := range customers {
+		tel, err := utils.NormalizePhone(customer.TelNo)
+		if err != nil {
+			report.InvalidPhone = append(report.InvalidPhone, customer)
+			continue
+		}
+		i, ok := groups[tel]
+		if !ok {
+			i = len(report.Duplicates)
+			groups[tel] = i
+			report.Duplicates = append(report.Duplicates, DuplicateCustomers{TelE164: tel})
+		}
+		report.Duplicates[i].Customers = append(report.Duplicates[i].Customers, customer)
+	}
+
+	duplicates := report.Duplicates[:0]
+	for _, group := range report.Duplicates {
+		if len(group.Customers) > 1 {
+			duplicates = append(duplicates, group)
+		}
+	}
+	report.Duplicates = duplicates
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Duplicate customers retrieved successfully",
+		Data:    report,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"main/models"
+
+	"gorm.io/gorm"
+)
+
+// Response structures
+type CustomerSummary struct {
+	CustomerID        uint            `json:"customer_id"`
+	FirstOrderAt      *time.Time      `json:"first_order_at"`
+	LastOrderAt       *time.Time      `json:"last_order_at"`
+	OrderCount        int64           `json:"order_count"` // Orders that were not cancelled
+	CancelledCount    int64           `json:"cancelled_count"`
+	LifetimeSpend     models.Money    `json:"lifetime_spend"` // Total of those orders less refunds
+	Refunded          models.Money    `json:"refunded"`
+	AverageOrderValue models.Money    `json:"average_order_value"`
+	FavouriteItems    []FavouriteItem `json:"favourite_items"`
+}
+
+// FavouriteItem is a line a customer orders often, e.g. "Large thin crust Margherita"
+type FavouriteItem struct {
+	ItemID        uint         `json:"item_id"`
+	PizzaID       *uint        `json:"pizza_id"`
+	BeverageID    *uint        `json:"beverage_id"`
+	ToppingID     *uint        `json:"topping_id"`
+	Description   string       `json:"description"`
+	Quantity      int64        `json:"quantity"`
+	OrderCount    int64        `json:"order_count"`
+	Spend         models.Money `json:"spend"`
+	LastOrderedAt time.Time    `json:"last_ordered_at"`
+}
+
+// findCustomer loads a customer that is not deleted
+func findCustomer(tx *gorm.DB, customerID uint) (models.Customer, error) {
+	var customer models.Customer
+	if err := tx.First(&customer, customerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customer, notFound("Customer not found")
+		}
+		return customer, err
+	}
+	return customer, nil
+}
+
+// customerSummary works out a customer's order statistics and favourite items
+func customerSummary(tx *gorm.DB, customerID uint, favourites int) (CustomerSummary, error) {
+	summary := CustomerSummary{CustomerID: customerID, FavouriteItems: []FavouriteItem{}}
+
+	var spend models.Money
+	row := tx.Model(&models.Order{}).
+		Select(`MIN(order_date) FILTER (WHERE order_status <> 'cancelled'),
+			MAX(order_date) FILTER (WHERE order_status <> 'cancelled'),
+			COUNT(*) FILTER (WHERE order_status <> 'cancelled'),
+			COUNT(*) FILTER (WHERE order_status = 'cancelled'),
+			COALESCE(SUM(total_amount) FILTER (WHERE order_status <> 'cancelled'), 0)`).
+		Where("customer_id = ?", customerID).
+		Row()
+	if err := row.Scan(&summary.FirstOrderAt, &summary.LastOrderAt, &summary.OrderCount, &summary.CancelledCount, &spend); err != nil {
+		return summary, err
+	}
+
+	if err := tx.Model(&models.Invoice{}).
+		Select("COALESCE(SUM(invoices.refunded_amount), 0)").
+		Joins("JOIN orders ON orders.id = invoices.order_id AND orders.deleted_at IS NULL").
+		Where("orders.customer_id = ? AND orders.order_status <> ?", customerID, "cancelled").
+		Scan(&summary.Refunded).Error; err != nil {
+		return summary, err
+	}
+
+	summary.LifetimeSpend = spend - summary.Refunded
+	if summary.OrderCount > 0 {
+		summary.AverageOrderValue = spend.Prorate(1, summary.OrderCount)
+	}
+
+	if err := tx.Table("order_items").
+		Select(`order_items.item_id, order_items.pizza_id, order_items.beverage_id, order_items.topping_id,
+			order_items.description,
+			SUM(order_items.quantity) AS quantity,
+			COUNT(DISTINCT orders.id) AS order_count,
+			SUM(order_items.total_price) AS spend,
+			MAX(orders.order_date) AS last_ordered_at`).
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Where("order_items.deleted_at IS NULL AND NOT order_items.delivery_fee").
+		Where("orders.customer_id = ? AND orders.order_status <> ?", customerID, "cancelled").
+		Group("order_items.item_id, order_items.pizza_id, order_items.beverage_id, order_items.topping_id, order_items.description").
+		Order("order_count DESC, quantity DESC, last_ordered_at DESC").
+		Limit(favourites).
+		Scan(&summary.FavouriteItems).Error; err != nil {
+		return summary, err
+	}
+
+	return summary, nil
+}
+
+// repeatOrder creates a new pending order with the same lines and toppings as
+// a previous order of the customer, priced from the current menu. Without
+// sourceID the customer's most recent order that was not cancelled is used.
+func repeatOrder(tx *gorm.DB, customerID uint, sourceID *uint, createdBy string) (models.Order, models.Order, error) {
+	var source models.Order
+	query := tx.Preload("OrderItems").Preload("OrderItems.Toppings").Where("customer_id = ?", customerID)
+	if sourceID != nil {
+		query = query.Where("id = ?", *sourceID)
+	} else {
+		query = query.Where("order_status <> ?", "cancelled").Order("order_date DESC, id DESC")
+	}
+	if err := query.First(&source).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if sourceID != nil {
+				return source, models.Order{}, notFound("Order %d not found for customer %d", *sourceID, customerID)
+			}
+			return source, models.Order{}, notFound("Customer %d has no previous order to repeat", customerID)
+		}
+		return source, models.Order{}, err
+	}
+
+	// A repeated dine-in order is taken away, the table is not known yet
+	req := CreateOrderRequest{CustomerID: customerID, CreatedBy: createdBy}
+	if source.FulfilmentType == "delivery" {
+		req.FulfilmentType = "delivery"
+		req.Courier = source.Courier
+	}
+	if source.FulfilmentType == "delivery" && source.DeliveryAddressID != nil {
+		// Deliver to the same place if the address is still saved, else to the default one
+		var saved int64
+		if err := tx.Model(&models.CustomerAddress{}).Where("id = ?", *source.DeliveryAddressID).Count(&saved).Error; err != nil {
+			return source, models.Order{}, err
+		}
+		if saved > 0 {
+			req.DeliveryAddressID = source.DeliveryAddressID
+		}
+	}
+	for _, line := range source.OrderItems {
+		if line.DeliveryFee {
+			continue // Charged again for the zone the new order goes to
+		}
+		item := CreateOrderItemRequest{
+			ItemID:     line.ItemID,
+			PizzaID:    line.PizzaID,
+			BeverageID: line.BeverageID,
+			ToppingID:  line.ToppingID,
+			Quantity:   line.Quantity,
+		}
+		for _, topping := range line.Toppings {
+			item.Toppings = append(item.Toppings, CreateOrderItemToppingRequest{
+				ToppingID: topping.ToppingID,
+				Action:    topping.Action,
+				Quantity:  topping.Quantity,
+			})
+		}
+		req.Items = append(req.Items, item)
+	}
+	if len(req.Items) == 0 {
+		return source, models.Order{}, conflict("Order %d has no lines to repeat", source.ID)
+	}
+
+	order, err := createOrder(tx, req)
+	return source, order, err
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"main/models"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type RepeatOrderRequest struct {
+	OrderID   *uint  `json:"order_id"`   // Order to repeat, defaults to the last one
+	CreatedBy string `json:"created_by"` // Staff member taking the order
+}
+
+type RepeatOrderResponse struct {
+	Order         models.Order `json:"order"`
+	RepeatedFrom  uint         `json:"repeated_from"`
+	PreviousTotal models.Money `json:"previous_total"`
+	PriceChange   models.Money `json:"price_change"` // New total less the previous total
+}
+
+func GetCustomerOrders(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/customers/%s/orders called", id)
+
+	customerID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid ID",
+		})
+		return
+	}
+
+	page := 1
+	limit := 10
+
+	if p := r.URL.Query().Get("page"); p != "" {
+		if pageNum, err := strconv.Atoi(p); err == nil && pageNum > 0 {
+			page = pageNum
+		}
+	}
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if limitNum, err := strconv.Atoi(l); err == nil && limitNum > 0 && limitNum <= 100 {
+			limit = limitNum
+		}
+	}
+
+	offset := (page - 1) * limit
+
+	if _, err := findCustomer(db, uint(customerID)); err != nil {
+		sendRequestError(w, err, "Failed to retrieve customer orders")
+		return
+	}
+
+	query := db.Model(&models.Order{}).Where("customer_id = ?", uint(customerID))
+
+	var totalCount int64
+	if err := query.Session(&gorm.Session{}).Count(&totalCount).Error; err != nil {
+		sendRequestError(w, err, "Failed to retrieve customer orders")
+		return
+	}
+
+	var orders []models.Order
+	if err := preloadOrderItems(query, "").
+		Offset(offset).
+		Limit(limit).
+		Order("order_date DESC, id DESC").
+		Find(&orders).Error; err != nil {
+		sendRequestError(w, err, "Failed to retrieve customer orders")
+		return
+	}
+
+	totalPages := int((totalCount + int64(limit) - 1) / int64(limit))
+
+	responseData := map[string]interface{}{
+		"orders": orders,
+		"pagination": map[string]interface{}{
+			"current_page": page,
+			"total_pages":  totalPages,
+			"total_count":  totalCount,
+			"limit":        limit,
+		},
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Customer orders retrieved successfully",
+		Data:    responseData,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetCustomerSummary(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/customers/%s/summary called", id)
+
+	customerID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid ID",
+		})
+		return
+	}
+
+	if _, err := findCustomer(db, uint(customerID)); err != nil {
+		sendRequestError(w, err, "Failed to retrieve customer summary")
+		return
+	}
+
+	summary, err := customerSummary(db, uint(customerID), 5)
+	if err != nil {
+		sendRequestError(w, err, "Failed to retrieve customer summary")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Customer summary retrieved successfully",
+		Data:    summary,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func RepeatLastOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/customers/%s/repeat-last-order called", id)
+
+	customerID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid ID",
+		})
+		return
+	}
+
+	// The body is optional
+	var req RepeatOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	var source, order models.Order
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if _, err := findCustomer(tx, uint(customerID)); err != nil {
+			return err
+		}
+		source, order, err = repeatOrder(tx, uint(customerID), req.OrderID, req.CreatedBy)
+		return err
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to repeat order")
+		return
+	}
+
+	if err := preloadOrderItems(db, "").First(&order, order.ID).Error; err != nil {
+		log.Printf("Error fetching repeated order: %v", err)
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Order repeated successfully",
+		Data: RepeatOrderResponse{
+			Order:         order,
+			RepeatedFrom:  source.ID,
+			PreviousTotal: source.TotalAmount,
+			PriceChange:   order.TotalAmount - source.TotalAmount,
+		},
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+// Response structures
+type DashboardStats struct {
+	Period            reportPeriod     `json:"period"`
+	Revenue           models.Money     `json:"revenue"` // Total of the orders that were not cancelled
+	OrderCount        int64            `json:"order_count"`
+	AverageOrderValue models.Money     `json:"average_order_value"`
+	OrdersByStatus    map[string]int64 `json:"orders_by_status"`
+	PaymentsReceived  models.Money     `json:"payments_received"`
+	Refunds           models.Money     `json:"refunds"`
+	Unpaid            UnpaidInvoices   `json:"unpaid_invoices"`
+	TopItems          []TopItem        `json:"top_items"`
+}
+
+// UnpaidInvoices is what is still owed right now, whatever the period
+type UnpaidInvoices struct {
+	Count        int64        `json:"count"`
+	Outstanding  models.Money `json:"outstanding"`
+	OverdueCount int64        `json:"overdue_count"`
+	Overdue      models.Money `json:"overdue"`
+}
+
+type TopItem struct {
+	ItemID   uint         `json:"item_id"`
+	Name     string       `json:"name"`
+	Type     string       `json:"type"`
+	Quantity int64        `json:"quantity"`
+	Sales    models.Money `json:"sales"`
+}
+
+func GetDashboardStats(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/dashboard/stats called")
+
+	period, err := parseReportPeriod(r)
+	if err != nil {
+		sendRequestError(w, err, "Failed to retrieve dashboard statistics")
+		return
+	}
+
+	top := 5
+	if topParam := r.URL.Query().Get("top"); topParam != "" {
+		if t, err := strconv.Atoi(topParam); err == nil && t > 0 && t <= 50 {
+			top = t
+		}
+	}
+
+	stats, err := dashboardStats(db, period, top)
+	if err != nil {
+		sendRequestError(w, err, "Failed to retrieve dashboard statistics")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Dashboard statistics retrieved successfully",
+		Data:    stats,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// dashboardStats aggregates orders, order lines, payments, credit notes and
+// invoices for the period. Orders count by order_date, payments by paid_at
+// and credit notes by credit_note_date.
+func dashboardStats(tx *gorm.DB, period reportPeriod, top int) (DashboardStats, error) {
+	stats := DashboardStats{
+		Period:         period,
+		OrdersByStatus: map[string]int64{},
+		TopItems:       []TopItem{},
+	}
+
+	var statuses []struct {
+		OrderStatus string
+		Count       int64
+		Revenue     models.Money
+	}
+	if err := tx.Model(&models.Order{}).
+		Select("order_status, COUNT(*) AS count, COALESCE(SUM(total_amount), 0) AS revenue").
+		Where("order_date >= ? AND order_date < ?", period.Start, period.End).
+		Group("order_status").
+		Scan(&statuses).Error; err != nil {
+		return stats, err
+	}
+	for _, status := range statuses {
+		stats.OrdersByStatus[status.OrderStatus] = status.Count
+		if status.OrderStatus != "cancelled" {
+			stats.OrderCount += status.Count
+			stats.Revenue += status.Revenue
+		}
+	}
+	if stats.OrderCount > 0 {
+		stats.AverageOrderValue = stats.Revenue.Prorate(1, stats.OrderCount)
+	}
+
+	if err := tx.Model(&models.Payment{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("reversed = ? AND paid_at >= ? AND paid_at < ?", false, period.Start, period.End).
+		Scan(&stats.PaymentsReceived).Error; err != nil {
+		return stats, err
+	}
+
+	var credited models.Money
+	if err := tx.Model(&models.CreditNote{}).
+		Select("COALESCE(SUM(total_amount), 0)").
+		Where("credit_note_date >= ? AND credit_note_date < ?", period.Start, period.End).
+		Scan(&credited).Error; err != nil {
+		return stats, err
+	}
+	stats.Refunds = -credited
+
+	if err := tx.Model(&models.Invoice{}).
+		Select(`COUNT(*) AS count,
+			COALESCE(SUM(total_amount - amount_paid), 0) AS outstanding,
+			COUNT(*) FILTER (WHERE payment_status = 'overdue') AS overdue_count,
+			COALESCE(SUM(total_amount - amount_paid) FILTER (WHERE payment_status = 'overdue'), 0) AS overdue`).
+		Where("payment_status IN ?", []string{"pending", "partially_paid", "overdue"}).
+		Scan(&stats.Unpaid).Error; err != nil {
+		return stats, err
+	}
+
+	if err := tx.Table("order_items").
+		Select(`order_items.item_id, items.name, items.type,
+			SUM(order_items.quantity) AS quantity,
+			COALESCE(SUM(order_items.total_price), 0) AS sales`).
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Joins("JOIN items ON items.id = order_items.item_id").
+		Where("order_items.deleted_at IS NULL AND NOT order_items.delivery_fee AND orders.order_status <> ?", "cancelled").
+		Where("orders.order_date >= ? AND orders.order_date < ?", period.Start, period.End).
+		Group("order_items.item_id, items.name, items.type").
+		Order("quantity DESC, sales DESC").
+		Limit(top).
+		Scan(&stats.TopItems).Error; err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	"main/models"
+
+	"gorm.io/gorm"
+)
+
+// findDeliveryZone returns the active zone containing the point. Where zones
+// overlap the one with the lowest fee wins, so a small inner zone can be drawn
+// on top of a wider, dearer one. It returns nil when no zone contains the point.
+func findDeliveryZone(tx *gorm.DB, latitude, longitude float64) (*models.DeliveryZone, error) {
+	var zones []models.DeliveryZone
+	if err := tx.Where("is_active = ?", true).Order("delivery_fee, id").Find(&zones).Error; err != nil {
+		return nil, err
+	}
+	for i := range zones {
+		if zones[i].Boundary.Contains(latitude, longitude) {
+			return &zones[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// applyDeliveryZone finds the zone a delivery order goes to and copies its
+// fee and delivery time onto the order. Without any active zones nothing can
+// be delivered, rather than everything for free. itemsTotal is the value of
+// the order's lines, checked against the zone's minimum order.
+func applyDeliveryZone(tx *gorm.DB, order *models.Order, itemsTotal models.Money) error {
+	if order.DeliveryLatitude == nil || order.DeliveryLongitude == nil {
+		return badRequest("The delivery address needs a latitude and longitude to find its delivery zone")
+	}
+
+	var zones int64
+	if err := tx.Model(&models.DeliveryZone{}).Where("is_active = ?", true).Count(&zones).Error; err != nil {
+		return err
+	}
+	if zones == 0 {
+		return conflict("No delivery zones configured; add one before taking delivery orders")
+	}
+
+	zone, err := findDeliveryZone(tx, *order.DeliveryLatitude, *order.DeliveryLongitude)
+	if err != nil {
+		return err
+	}
+	if zone == nil {
+		return badRequest("The delivery address is outside the delivery area")
+	}
+	if itemsTotal < zone.MinimumOrder {
+		return badRequest("Orders delivered to %s must be at least %s", zone.Name, zone.MinimumOrder)
+	}
+
+	order.DeliveryZoneID = &zone.ID
+	order.DeliveryFee = zone.DeliveryFee
+	order.EstimatedMinutes = zone.EstimatedMinutes
+	return nil
+}
+
+// checkDeliveryMinimum makes sure an amended delivery order still reaches its
+// zone's minimum order
+func checkDeliveryMinimum(tx *gorm.DB, order models.Order) error {
+	if order.DeliveryZoneID == nil {
+		return nil
+	}
+	var zone models.DeliveryZone
+	if err := tx.Unscoped().First(&zone, *order.DeliveryZoneID).Error; err != nil {
+		return err
+	}
+	var itemsTotal models.Money
+	for _, orderItem := range order.OrderItems {
+		if !orderItem.DeliveryFee {
+			itemsTotal += orderItem.TotalPrice
+		}
+	}
+	if itemsTotal < zone.MinimumOrder {
+		return conflict("Orders delivered to %s must be at least %s", zone.Name, zone.MinimumOrder)
+	}
+	return nil
+}
+
+// deliveryFeeLine builds the order line billing a delivery fee. It points at
+// the inactive "Delivery fee" item of type delivery seeded on start, so the
+// fee is taxed through the delivery tax class (else the default class) and
+// appears on invoices, receipts, credit notes and reports like any line.
+func deliveryFeeLine(tx *gorm.DB, fee models.Money) (models.OrderItem, error) {
+	var item models.Item
+	err := tx.Unscoped().Where("type = ? AND name = ?", "delivery", models.DeliveryFeeItemName).Order("id").First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.OrderItem{}, fmt.Errorf("the %q item is missing; restart the server to seed it", models.DeliveryFeeItemName)
+	}
+	if err != nil {
+		return models.OrderItem{}, err
+	}
+
+	return models.OrderItem{
+		ItemID:      item.ID,
+		Description: models.DeliveryFeeItemName,
+		Quantity:    1,
+		UnitPrice:   fee,
+		TotalPrice:  fee,
+		DeliveryFee: true,
+	}, nil
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"main/models"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// DeliveryZoneRequest creates a zone, or changes the fields that are present
+type DeliveryZoneRequest struct {
+	Name             *string             `json:"name"`
+	Boundary         *models.GeoBoundary `json:"boundary"` // GeoJSON Polygon or MultiPolygon, [longitude, latitude]
+	DeliveryFee      *models.Money       `json:"delivery_fee"`
+	MinimumOrder     *models.Money       `json:"minimum_order"`
+	EstimatedMinutes *int                `json:"estimated_minutes"`
+	IsActive         *bool               `json:"is_active"`
+}
+
+// apply copies the fields that are present onto the zone and returns what is
+// wrong with the result, if anything
+func (req DeliveryZoneRequest) apply(zone *models.DeliveryZone) string {
+	if req.Name != nil {
+		zone.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Boundary != nil {
+		zone.Boundary = *req.Boundary
+	}
+	if req.DeliveryFee != nil {
+		zone.DeliveryFee = *req.DeliveryFee
+	}
+	if req.MinimumOrder != nil {
+		zone.MinimumOrder = *req.MinimumOrder
+	}
+	if req.EstimatedMinutes != nil {
+		zone.EstimatedMinutes = *req.EstimatedMinutes
+	}
+	if req.IsActive != nil {
+		zone.IsActive = *req.IsActive
+	}
+
+	switch {
+	case zone.Name == "":
+		return "Name is required"
+	case len(zone.Boundary) == 0:
+		return "Boundary is required"
+	case zone.DeliveryFee < 0 || zone.MinimumOrder < 0:
+		return "Delivery fee and minimum order cannot be negative"
+	case zone.EstimatedMinutes < 0:
+		return "Estimated minutes cannot be negative"
+	}
+	return ""
+}
+
+// DeliveryZoneCheck answers whether a location can be delivered to
+type DeliveryZoneCheck struct {
+	Deliverable bool                 `json:"deliverable"`
+	Zone        *models.DeliveryZone `json:"zone"`
+}
+
+func GetDeliveryZones(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/delivery-zones called")
+
+	var zones []models.DeliveryZone
+	if err := db.Order("name").Find(&zones).Error; err != nil {
+		sendRequestError(w, err, "Failed to retrieve delivery zones")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Delivery zones retrieved successfully",
+		Data:    zones,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetDeliveryZoneByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/delivery-zones/%s called", id)
+
+	zoneID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid delivery zone ID"), "")
+		return
+	}
+
+	var zone models.DeliveryZone
+	if err := db.First(&zone, uint(zoneID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = notFound("Delivery zone not found")
+		}
+		sendRequestError(w, err, "Failed to retrieve delivery zone")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Delivery zone retrieved successfully",
+		Data:    zone,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func CreateDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /api/delivery-zones called")
+
+	var req DeliveryZoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendRequestError(w, badRequest("Invalid request body: %v", err), "")
+		return
+	}
+
+	zone := models.DeliveryZone{IsActive: true}
+	if msg := req.apply(&zone); msg != "" {
+		sendRequestError(w, badRequest("%s", msg), "")
+		return
+	}
+
+	// is_active has a database default, so it is set explicitly
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&zone).Error; err != nil {
+			return err
+		}
+		return tx.Model(&zone).Update("is_active", zone.IsActive).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to create delivery zone")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Delivery zone created successfully",
+		Data:    zone,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func UpdateDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("PUT /api/delivery-zones/%s called", id)
+
+	zoneID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid delivery zone ID"), "")
+		return
+	}
+
+	var req DeliveryZoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendRequestError(w, badRequest("Invalid request body: %v", err), "")
+		return
+	}
+
+	var zone models.DeliveryZone
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&zone, uint(zoneID)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return notFound("Delivery zone not found")
+			}
+			return err
+		}
+		if msg := req.apply(&zone); msg != "" {
+			return badRequest("%s", msg)
+		}
+		return tx.Model(&zone).
+			Select("name", "boundary", "delivery_fee", "minimum_order", "estimated_minutes", "is_active").
+			Updates(&zone).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to update delivery zone")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Delivery zone updated successfully",
+		Data:    zone,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// DeleteDeliveryZone soft deletes a zone; orders keep the fee they were charged
+func DeleteDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("DELETE /api/delivery-zones/%s called", id)
+
+	zoneID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid delivery zone ID"), "")
+		return
+	}
+
+	result := db.Delete(&models.DeliveryZone{}, uint(zoneID))
+	if result.Error != nil {
+		sendRequestError(w, result.Error, "Failed to delete delivery zone")
+		return
+	}
+	if result.RowsAffected == 0 {
+		sendRequestError(w, notFound("Delivery zone not found"), "")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Delivery zone deleted successfully",
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// CheckDeliveryZone tells the order taker whether a location is in the
+// delivery area, and at what fee, before the order is placed
+func CheckDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/delivery-zones/check called")
+
+	latitude, latErr := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	longitude, lngErr := strconv.ParseFloat(r.URL.Query().Get("lng"), 64)
+	if latErr != nil || lngErr != nil {
+		sendRequestError(w, badRequest("lat and lng are required"), "")
+		return
+	}
+
+	zone, err := findDeliveryZone(db, latitude, longitude)
+	if err != nil {
+		sendRequestError(w, err, "Failed to check delivery zone")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Delivery zone checked successfully",
+		Data:    DeliveryZoneCheck{Deliverable: zone != nil, Zone: zone},
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"main/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DriverSettlement is what a driver handed in on returning to the shop
+type DriverSettlement struct {
+	Driver      models.Driver               `json:"driver"`
+	Assignments []models.DeliveryAssignment `json:"assignments"` // Settled now
+	Expected    models.Money                `json:"expected"`    // Cash due on the delivered orders
+	Collected   models.Money                `json:"collected"`
+	Difference  models.Money                `json:"difference"` // Collected - Expected; negative when cash is short
+}
+
+// cashToCollect is the cash a driver should bring back for an order: the
+// balance of its invoice rounded for cash, or the order total while the order
+// has no invoice
+func cashToCollect(tx *gorm.DB, orderID uint) (models.Money, error) {
+	var invoice models.Invoice
+	err := tx.Where("order_id = ?", orderID).First(&invoice).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var order models.Order
+		if err := tx.First(&order, orderID).Error; err != nil {
+			return 0, err
+		}
+		return cashDue(order.TotalAmount), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if invoice.PaymentStatus == "cancelled" || invoice.PaymentStatus == "refunded" {
+		return 0, nil
+	}
+	if due := cashDue(invoice.TotalAmount - invoice.AmountPaid); due > 0 {
+		return due, nil
+	}
+	return 0, nil
+}
+
+// openAssignment loads and locks the assignment of an order still on its way;
+// it returns nil when the order has none
+func openAssignment(tx *gorm.DB, orderID uint) (*models.DeliveryAssignment, error) {
+	var assignment models.DeliveryAssignment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status IN ?", orderID, openAssignmentStatuses).
+		First(&assignment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// assignDriver gives a locked, ready in-house delivery order to a driver, or to
+// the driver pickDriver chooses when driverID is nil. Until the order goes out
+// it can be given to another driver.
+func assignDriver(tx *gorm.DB, order *models.Order, driverID *uint, assignedBy string) (models.DeliveryAssignment, error) {
+	assignment := models.DeliveryAssignment{
+		OrderID:      order.ID,
+		Status:       "assigned",
+		AutoAssigned: driverID == nil,
+		AssignedBy:   strings.TrimSpace(assignedBy),
+		AssignedAt:   time.Now(),
+	}
+
+	if order.FulfilmentType != "delivery" {
+		return assignment, conflict("Order %d is not a delivery order", order.ID)
+	}
+	if order.Courier != "in_house" {
+		return assignment, conflict("Order %d is delivered by %s", order.ID, order.Courier)
+	}
+	if order.OrderStatus != "ready" {
+		return assignment, conflict("Order %d is %s; only ready orders can be assigned to a driver", order.ID, order.OrderStatus)
+	}
+
+	var driver models.Driver
+	var err error
+	if driverID != nil {
+		if driver, err = lockDriver(tx, *driverID); err != nil {
+			return assignment, err
+		}
+		if driver.Status == "off_duty" {
+			return assignment, conflict("Driver %s is off duty", driver.Name)
+		}
+	} else if driver, err = pickDriver(tx, *order); err != nil {
+		return assignment, err
+	}
+
+	current, err := openAssignment(tx, order.ID)
+	if err != nil {
+		return assignment, err
+	}
+	if current != nil {
+		if current.DriverID == driver.ID {
+			return assignment, conflict("Order %d is already assigned to %s", order.ID, driver.Name)
+		}
+		if err := tx.Model(current).Updates(map[string]interface{}{
+			"status":       "cancelled",
+			"cancelled_at": assignment.AssignedAt,
+		}).Error; err != nil {
+			return assignment, err
+		}
+	}
+
+	if assignment.CashToCollect, err = cashToCollect(tx, order.ID); err != nil {
+		return assignment, err
+	}
+	assignment.DriverID = driver.ID
+	if err := tx.Create(&assignment).Error; err != nil {
+		return assignment, err
+	}
+	assignment.Driver = &driver
+
+	order.DriverID = &driver.ID
+	return assignment, tx.Model(&models.Order{}).Where("id = ?", order.ID).Update("driver_id", order.DriverID).Error
+}
+
+// updateDeliveryAssignment follows an order's status change on its open
+// assignment. In-house delivery orders need a driver before they go out, and
+// the driver is then on delivery until they settle up.
+func updateDeliveryAssignment(tx *gorm.DB, order *models.Order, to string, at time.Time) error {
+	if order.FulfilmentType != "delivery" {
+		return nil
+	}
+	if to != "out_for_delivery" && to != "delivered" && to != "cancelled" {
+		return nil
+	}
+
+	assignment, err := openAssignment(tx, order.ID)
+	if err != nil {
+		return err
+	}
+	if assignment == nil {
+		if to == "out_for_delivery" && order.Courier == "in_house" {
+			return conflict("Assign a driver to order %d before it goes out for delivery", order.ID)
+		}
+		return nil
+	}
+
+	switch to {
+	case "out_for_delivery":
+		cash, err := cashToCollect(tx, order.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(assignment).Updates(map[string]interface{}{
+			"status":          "out_for_delivery",
+			"dispatched_at":   at,
+			"cash_to_collect": cash,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Driver{}).Where("id = ?", assignment.DriverID).Update("status", "on_delivery").Error
+
+	case "delivered":
+		if assignment.Status != "out_for_delivery" {
+			return nil
+		}
+		return tx.Model(assignment).Updates(map[string]interface{}{
+			"status":       "delivered",
+			"delivered_at": at,
+		}).Error
+
+	default:
+		// A driver out with a cancelled order brings it back; there is no cash to settle
+		return tx.Model(assignment).Updates(map[string]interface{}{
+			"status":       "cancelled",
+			"cancelled_at": at,
+		}).Error
+	}
+}
+
+// settleDriver books the cash a returning driver hands in. Each delivered
+// order's collection is recorded as a cash payment on its invoice, up to the
+// cash due so the invoice is not overpaid, and the difference with the cash
+// due, short or over, is kept on the assignment. The driver is available
+// again afterwards.
+func settleDriver(tx *gorm.DB, driver *models.Driver, req SettleDriverRequest) (DriverSettlement, error) {
+	settlement := DriverSettlement{Assignments: []models.DeliveryAssignment{}}
+	settledBy := strings.TrimSpace(req.ReceivedBy)
+
+	var out models.DeliveryAssignment
+	err := tx.Where("driver_id = ? AND status = ?", driver.ID, "out_for_delivery").Order("id").First(&out).Error
+	if err == nil {
+		return settlement, conflict("Driver %s still has order %d out for delivery; mark it delivered or cancelled first", driver.Name, out.OrderID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return settlement, err
+	}
+
+	var delivered []models.DeliveryAssignment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("driver_id = ? AND status = ?", driver.ID, "delivered").
+		Order("id").
+		Find(&delivered).Error; err != nil {
+		return settlement, err
+	}
+	pending := map[uint]bool{}
+	for _, assignment := range delivered {
+		pending[assignment.OrderID] = true
+	}
+
+	collections := map[uint]models.Money{}
+	for _, collection := range req.Collections {
+		if !pending[collection.OrderID] {
+			return settlement, badRequest("Order %d is not a delivered order of %s awaiting settlement", collection.OrderID, driver.Name)
+		}
+		if collection.Amount < 0 {
+			return settlement, badRequest("Amount cannot be negative for order %d", collection.OrderID)
+		}
+		if _, ok := collections[collection.OrderID]; ok {
+			return settlement, badRequest("Order %d is listed more than once", collection.OrderID)
+		}
+		collections[collection.OrderID] = collection.Amount
+	}
+
+	now := time.Now()
+	for i := range delivered {
+		assignment := &delivered[i]
+		collected := collections[assignment.OrderID]
+
+		invoice, err := lockInvoice(tx, "order_id = ?", assignment.OrderID)
+		if err != nil {
+			return settlement, err
+		}
+		if invoice == nil && collected > 0 {
+			return settlement, conflict("Order %d has no invoice; create it before settling the cash collected", assignment.OrderID)
+		}
+		expected, err := cashToCollect(tx, assignment.OrderID)
+		if err != nil {
+			return settlement, err
+		}
+
+		if paid := min(collected, expected); paid > 0 {
+			payment, err := recordPayment(tx, invoice, RecordPaymentRequest{
+				Method:        "cash",
+				Amount:        paid,
+				Reference:     fmt.Sprintf("Cash on delivery, %s", driver.Name),
+				ReceivedBy:    settledBy,
+				CashSessionID: req.CashSessionID,
+			})
+			if err != nil {
+				return settlement, err
+			}
+			assignment.PaymentID = &payment.ID
+		}
+
+		difference := collected - expected
+		assignment.Status = "settled"
+		assignment.CashCollected = &collected
+		assignment.CashDifference = &difference
+		assignment.SettledAt = &now
+		assignment.SettledBy = settledBy
+		if err := tx.Model(assignment).Updates(map[string]interface{}{
+			"status":          assignment.Status,
+			"cash_collected":  assignment.CashCollected,
+			"cash_difference": assignment.CashDifference,
+			"payment_id":      assignment.PaymentID,
+			"settled_at":      assignment.SettledAt,
+			"settled_by":      assignment.SettledBy,
+		}).Error; err != nil {
+			return settlement, err
+		}
+
+		settlement.Expected += expected
+		settlement.Collected += collected
+		settlement.Assignments = append(settlement.Assignments, *assignment)
+	}
+	settlement.Difference = settlement.Collected - settlement.Expected
+
+	if driver.Status == "on_delivery" {
+		driver.Status = "available"
+		if err := tx.Model(driver).Update("status", driver.Status).Error; err != nil {
+			return settlement, err
+		}
+	}
+	settlement.Driver = *driver
+	return settlement, nil
+}
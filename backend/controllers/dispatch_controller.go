@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main/models"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type AssignDriverRequest struct {
+	DriverID   *uint  `json:"driver_id"` // Leave out to pick the least busy, nearest available driver
+	AssignedBy string `json:"assigned_by"`
+}
+
+type SettleDriverRequest struct {
+	Collections []DriverCollection `json:"collections"` // Cash handed in per delivered order; orders left out collected nothing
+	ReceivedBy  string             `json:"received_by"` // Staff member counting the cash
+
+	CashSessionID *uint `json:"cash_session_id"` // Drawer session, defaults to received_by's open session
+}
+
+type DriverCollection struct {
+	OrderID uint         `json:"order_id"`
+	Amount  models.Money `json:"amount"`
+}
+
+// OrderTracking is where a delivery order is and when it should arrive
+type OrderTracking struct {
+	OrderID          uint                       `json:"order_id"`
+	OrderStatus      string                     `json:"order_status"`
+	OutForDeliveryAt *time.Time                 `json:"out_for_delivery_at"`
+	EstimatedArrival *time.Time                 `json:"estimated_arrival"` // Departure plus the zone's estimated minutes
+	Assignment       *models.DeliveryAssignment `json:"assignment"`        // With the driver and their last location
+}
+
+// AssignOrderDriver gives a ready delivery order to a driver, picking one
+// when no driver_id is given
+func AssignOrderDriver(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/orders/%s/driver called", id)
+
+	orderID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid order ID"), "")
+		return
+	}
+
+	// The body is optional: no body auto-assigns
+	var req AssignDriverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		sendRequestError(w, badRequest("Invalid request body"), "")
+		return
+	}
+
+	var assignment models.DeliveryAssignment
+	err = db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, uint(orderID))
+		if err != nil {
+			return err
+		}
+		assignment, err = assignDriver(tx, &order, req.DriverID, req.AssignedBy)
+		return err
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to assign driver")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Driver assigned successfully",
+		Data:    assignment,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+// GetDeliveryAssignments lists assignments, newest first, optionally filtered
+// by ?driver_id= and ?status=
+func GetDeliveryAssignments(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/deliveries called")
+
+	query := db.Preload("Order").Preload("Driver").Order("assigned_at DESC, id DESC")
+	if driverParam := r.URL.Query().Get("driver_id"); driverParam != "" {
+		driverID, err := strconv.ParseUint(driverParam, 10, 32)
+		if err != nil {
+			sendRequestError(w, badRequest("Invalid driver_id"), "")
+			return
+		}
+		query = query.Where("driver_id = ?", uint(driverID))
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var assignments []models.DeliveryAssignment
+	if err := query.Find(&assignments).Error; err != nil {
+		sendRequestError(w, err, "Failed to retrieve deliveries")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Deliveries retrieved successfully",
+		Data:    assignments,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// SettleDriver books the cash on delivery a driver hands in on their return
+func SettleDriver(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/drivers/%s/settle called", id)
+
+	driverID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid driver ID"), "")
+		return
+	}
+
+	var req SettleDriverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendRequestError(w, badRequest("Invalid request body"), "")
+		return
+	}
+
+	var settlement DriverSettlement
+	err = db.Transaction(func(tx *gorm.DB) error {
+		driver, err := lockDriver(tx, uint(driverID))
+		if err != nil {
+			return err
+		}
+		settlement, err = settleDriver(tx, &driver, req)
+		return err
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to settle driver")
+		return
+	}
+	if settlement.Difference != 0 {
+		log.Printf("Driver %d settled with a cash difference of %s", settlement.Driver.ID, settlement.Difference)
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Driver settled successfully",
+		Data:    settlement,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetOrderTracking(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/orders/%s/tracking called", id)
+
+	orderID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid order ID"), "")
+		return
+	}
+
+	var order models.Order
+	if err := db.First(&order, uint(orderID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = notFound("Order not found")
+		}
+		sendRequestError(w, err, "Failed to retrieve order tracking")
+		return
+	}
+	if order.FulfilmentType != "delivery" {
+		sendRequestError(w, conflict("Order %d is not a delivery order", order.ID), "")
+		return
+	}
+
+	tracking := OrderTracking{
+		OrderID:          order.ID,
+		OrderStatus:      order.OrderStatus,
+		OutForDeliveryAt: order.OutForDeliveryAt,
+	}
+	if order.OutForDeliveryAt != nil && order.EstimatedMinutes > 0 {
+		arrival := order.OutForDeliveryAt.Add(time.Duration(order.EstimatedMinutes) * time.Minute)
+		tracking.EstimatedArrival = &arrival
+	}
+
+	// The latest assignment that was not handed to another driver
+	var assignment models.DeliveryAssignment
+	err = db.Preload("Driver").
+		Where("order_id = ? AND status <> ?", order.ID, "cancelled").
+		Order("id DESC").
+		First(&assignment).Error
+	if err == nil {
+		tracking.Assignment = &assignment
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		sendRequestError(w, err, "Failed to retrieve order tracking")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Order tracking retrieved successfully",
+		Data:    tracking,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// validDriverStatuses are the states a driver on the roster can be in.
+// on_delivery is set when one of their orders goes out and cleared when
+// they settle up.
+var validDriverStatuses = map[string]bool{
+	"available":   true,
+	"on_delivery": true,
+	"off_duty":    true,
+}
+
+// openAssignmentStatuses are the assignment statuses of an order still on its way
+var openAssignmentStatuses = []string{"assigned", "out_for_delivery"}
+
+// unsettledAssignmentStatuses are the assignment statuses a driver still has to answer for
+var unsettledAssignmentStatuses = []string{"assigned", "out_for_delivery", "delivered"}
+
+// validateDriver trims a driver's fields and normalises the phone number to
+// E.164; it returns "" when the driver is valid
+func validateDriver(driver *models.Driver) string {
+	for _, field := range []*string{&driver.Name, &driver.Phone, &driver.Vehicle} {
+		*field = strings.TrimSpace(*field)
+	}
+
+	if driver.Name == "" {
+		return "Name is required"
+	}
+	if driver.Phone != "" {
+		phone, err := utils.NormalizePhone(driver.Phone)
+		if err != nil {
+			return "Invalid phone number " + driver.Phone
+		}
+		driver.Phone = phone
+	}
+	return ""
+}
+
+// lockDriver loads a driver and locks its row until the transaction ends
+func lockDriver(tx *gorm.DB, driverID uint) (models.Driver, error) {
+	var driver models.Driver
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&driver, driverID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return driver, notFound("Driver %d not found", driverID)
+	}
+	return driver, err
+}
+
+// recordDriverLocation stores a location ping. Pings can arrive out of order,
+// so the driver's last location only moves forward in time.
+func recordDriverLocation(tx *gorm.DB, driver *models.Driver, latitude, longitude float64, recordedAt time.Time) (models.DriverLocation, error) {
+	location := models.DriverLocation{
+		DriverID:   driver.ID,
+		Latitude:   latitude,
+		Longitude:  longitude,
+		RecordedAt: recordedAt,
+	}
+
+	if latitude < -90 || latitude > 90 {
+		return location, badRequest("Latitude must be between -90 and 90")
+	}
+	if longitude < -180 || longitude > 180 {
+		return location, badRequest("Longitude must be between -180 and 180")
+	}
+	if recordedAt.After(time.Now().Add(time.Minute)) {
+		return location, badRequest("recorded_at cannot be in the future")
+	}
+
+	if err := tx.Create(&location).Error; err != nil {
+		return location, err
+	}
+	if driver.LastSeenAt != nil && !recordedAt.After(*driver.LastSeenAt) {
+		return location, nil
+	}
+
+	driver.LastLatitude = &location.Latitude
+	driver.LastLongitude = &location.Longitude
+	driver.LastSeenAt = &location.RecordedAt
+	return location, tx.Model(driver).Updates(map[string]interface{}{
+		"last_latitude":  driver.LastLatitude,
+		"last_longitude": driver.LastLongitude,
+		"last_seen_at":   driver.LastSeenAt,
+	}).Error
+}
+
+// pickDriver chooses a driver for an order: of the available drivers, the one
+// with the fewest open assignments, then the one nearest the delivery address
+// by their last location (drivers without one come last), then the longest on
+// the roster. The chosen driver's row is locked.
+func pickDriver(tx *gorm.DB, order models.Order) (models.Driver, error) {
+	var drivers []models.Driver
+	if err := tx.Where("status = ?", "available").Order("id").Find(&drivers).Error; err != nil {
+		return models.Driver{}, err
+	}
+	if len(drivers) == 0 {
+		return models.Driver{}, conflict("No driver is available")
+	}
+
+	var loads []struct {
+		DriverID uint
+		Count    int
+	}
+	if err := tx.Model(&models.DeliveryAssignment{}).
+		Select("driver_id, COUNT(*) AS count").
+		Where("status IN ?", openAssignmentStatuses).
+		Group("driver_id").
+		Scan(&loads).Error; err != nil {
+		return models.Driver{}, err
+	}
+	load := map[uint]int{}
+	for _, row := range loads {
+		load[row.DriverID] = row.Count
+	}
+
+	distance := func(driver models.Driver) float64 {
+		if driver.LastLatitude == nil || order.DeliveryLatitude == nil || order.DeliveryLongitude == nil {
+			return math.Inf(1)
+		}
+		return distanceKm(*driver.LastLatitude, *driver.LastLongitude, *order.DeliveryLatitude, *order.DeliveryLongitude)
+	}
+	sort.SliceStable(drivers, func(i, j int) bool {
+		if load[drivers[i].ID] != load[drivers[j].ID] {
+			return load[drivers[i].ID] < load[drivers[j].ID]
+		}
+		return distance(drivers[i]) < distance(drivers[j])
+	})
+
+	// The driver may have gone off duty since the roster was read
+	driver, err := lockDriver(tx, drivers[0].ID)
+	if err != nil {
+		return driver, err
+	}
+	if driver.Status != "available" {
+		return driver, conflict("Driver %s is no longer available, try again", driver.Name)
+	}
+	return driver, nil
+}
+
+// distanceKm is the great-circle distance between two points
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
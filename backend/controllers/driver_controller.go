@@ -0,0 +1,348 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/models"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// DriverRequest adds a driver to the roster, or changes the fields that are present
+type DriverRequest struct {
+	Name    *string `json:"name"`
+	Phone   *string `json:"phone"`
+	Vehicle *string `json:"vehicle"`
+	Status  *string `json:"status"` // available or off_duty; on_delivery is set by dispatch
+}
+
+// apply copies the fields that are present onto the driver
+func (req DriverRequest) apply(driver *models.Driver) {
+	for _, field := range []struct {
+		value *string
+		to    *string
+	}{
+		{req.Name, &driver.Name},
+		{req.Phone, &driver.Phone},
+		{req.Vehicle, &driver.Vehicle},
+	} {
+		if field.value != nil {
+			*field.to = *field.value
+		}
+	}
+}
+
+type DriverLocationRequest struct {
+	Latitude   *float64   `json:"latitude"`
+	Longitude  *float64   `json:"longitude"`
+	RecordedAt *time.Time `json:"recorded_at"` // When the phone took the fix, defaults to now
+}
+
+// changeDriverStatus moves a locked driver between available and off duty.
+// Drivers on delivery change status by settling up, and drivers with orders
+// still to deliver or settle cannot go off duty.
+func changeDriverStatus(tx *gorm.DB, driver *models.Driver, status string) error {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if !validDriverStatuses[status] || status == "on_delivery" {
+		return badRequest("Invalid driver status %q. Must be one of: available, off_duty", status)
+	}
+	if status == driver.Status {
+		return nil
+	}
+	if driver.Status == "on_delivery" {
+		return conflict("Driver %s is on delivery; settle their return first", driver.Name)
+	}
+
+	if status == "off_duty" {
+		var open int64
+		if err := tx.Model(&models.DeliveryAssignment{}).
+			Where("driver_id = ? AND status IN ?", driver.ID, unsettledAssignmentStatuses).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return conflict("Driver %s has %d order(s) to deliver or settle", driver.Name, open)
+		}
+	}
+	driver.Status = status
+	return nil
+}
+
+func GetDrivers(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/drivers called")
+
+	query := db.Order("name")
+	if status := r.URL.Query().Get("status"); status != "" {
+		if !validDriverStatuses[status] {
+			sendRequestError(w, badRequest("Invalid driver status %q. Must be one of: available, on_delivery, off_duty", status), "")
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var drivers []models.Driver
+	if err := query.Find(&drivers).Error; err != nil {
+		sendRequestError(w, err, "Failed to retrieve drivers")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Drivers retrieved successfully",
+		Data:    drivers,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetDriverByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/drivers/%s called", id)
+
+	driverID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid driver ID"), "")
+		return
+	}
+
+	var driver models.Driver
+	if err := db.First(&driver, uint(driverID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = notFound("Driver not found")
+		}
+		sendRequestError(w, err, "Failed to retrieve driver")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Driver retrieved successfully",
+		Data:    driver,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func CreateDriver(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /api/drivers called")
+
+	var req DriverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendRequestError(w, badRequest("Invalid request body"), "")
+		return
+	}
+
+	driver := models.Driver{Status: "available"}
+	req.apply(&driver)
+	if msg := validateDriver(&driver); msg != "" {
+		sendRequestError(w, badRequest("%s", msg), "")
+		return
+	}
+	if req.Status != nil {
+		if err := changeDriverStatus(db, &driver, *req.Status); err != nil {
+			sendRequestError(w, err, "Failed to create driver")
+			return
+		}
+	}
+
+	if err := db.Create(&driver).Error; err != nil {
+		sendRequestError(w, err, "Failed to create driver")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Driver created successfully",
+		Data:    driver,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func UpdateDriver(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("PUT /api/drivers/%s called", id)
+
+	driverID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid driver ID"), "")
+		return
+	}
+
+	var req DriverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendRequestError(w, badRequest("Invalid request body"), "")
+		return
+	}
+
+	var driver models.Driver
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if driver, err = lockDriver(tx, uint(driverID)); err != nil {
+			return err
+		}
+		req.apply(&driver)
+		if msg := validateDriver(&driver); msg != "" {
+			return badRequest("%s", msg)
+		}
+		if req.Status != nil {
+			if err := changeDriverStatus(tx, &driver, *req.Status); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&driver).Select("name", "phone", "vehicle", "status").Updates(&driver).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to update driver")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Driver updated successfully",
+		Data:    driver,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// DeleteDriver soft deletes a driver who has nothing left to deliver or settle
+func DeleteDriver(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("DELETE /api/drivers/%s called", id)
+
+	driverID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid driver ID"), "")
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		driver, err := lockDriver(tx, uint(driverID))
+		if err != nil {
+			return err
+		}
+
+		var open int64
+		if err := tx.Model(&models.DeliveryAssignment{}).
+			Where("driver_id = ? AND status IN ?", driver.ID, unsettledAssignmentStatuses).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return conflict("Driver %s has %d order(s) to deliver or settle", driver.Name, open)
+		}
+		return tx.Delete(&driver).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to delete driver")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Driver deleted successfully",
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// RecordDriverLocation takes a location ping from a driver's phone
+func RecordDriverLocation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/drivers/%s/locations called", id)
+
+	driverID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid driver ID"), "")
+		return
+	}
+
+	var req DriverLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendRequestError(w, badRequest("Invalid request body"), "")
+		return
+	}
+	if req.Latitude == nil || req.Longitude == nil {
+		sendRequestError(w, badRequest("latitude and longitude are required"), "")
+		return
+	}
+	recordedAt := time.Now()
+	if req.RecordedAt != nil {
+		recordedAt = *req.RecordedAt
+	}
+
+	var location models.DriverLocation
+	err = db.Transaction(func(tx *gorm.DB) error {
+		driver, err := lockDriver(tx, uint(driverID))
+		if err != nil {
+			return err
+		}
+		location, err = recordDriverLocation(tx, &driver, *req.Latitude, *req.Longitude, recordedAt)
+		return err
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to record driver location")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Driver location recorded successfully",
+		Data:    location,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+// GetDriverLocations returns a driver's trail since ?since= (RFC 3339),
+// by default over the last hour
+func GetDriverLocations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/drivers/%s/locations called", id)
+
+	driverID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		sendRequestError(w, badRequest("Invalid driver ID"), "")
+		return
+	}
+
+	since := time.Now().Add(-time.Hour)
+	if sinceParam := r.URL.Query().Get("since"); sinceParam != "" {
+		if since, err = time.Parse(time.RFC3339, sinceParam); err != nil {
+			sendRequestError(w, badRequest("Invalid since %q, use RFC 3339 e.g. 2025-03-01T18:00:00+05:30", sinceParam), "")
+			return
+		}
+	}
+
+	var driver models.Driver
+	if err := db.First(&driver, uint(driverID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = notFound("Driver not found")
+		}
+		sendRequestError(w, err, "Failed to retrieve driver locations")
+		return
+	}
+
+	var locations []models.DriverLocation
+	if err := db.Where("driver_id = ? AND recorded_at >= ?", driver.ID, since).
+		Order("recorded_at").
+		Find(&locations).Error; err != nil {
+		sendRequestError(w, err, "Failed to retrieve driver locations")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Driver locations retrieved successfully",
+		Data:    locations,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"main/utils"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// requestError is a problem caused by the request itself (unknown item,
+// price mismatch, ...). Handlers answer it with its Status instead of a 500.
+type requestError struct {
+	Status  int
+	Message string
+}
+
+func (e *requestError) Error() string {
+	return e.Message
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &requestError{Status: http.StatusBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &requestError{Status: http.StatusNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func conflict(format string, args ...interface{}) error {
+	return &requestError{Status: http.StatusConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// sendRequestError answers an error from a multi-step operation: request errors
+// keep their own status and message, anything else is logged and reported as a 500.
+func sendRequestError(w http.ResponseWriter, err error, fallback string) {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		utils.SendJSONResponse(w, reqErr.Status, utils.APIResponse{
+			Success: false,
+			Message: reqErr.Message,
+			Data:    nil,
+		})
+		return
+	}
+
+	log.Printf("%s: %v", fallback, err)
+	utils.SendJSONResponse(w, http.StatusInternalServerError, utils.APIResponse{
+		Success: false,
+		Message: fallback,
+		Data:    nil,
+	})
+}
+
+// isUniqueViolation reports whether err is PostgreSQL refusing a duplicate
+// value for a unique index, e.g. two requests racing to create the same row
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"main/models"
+
+	"gorm.io/gorm"
+)
+
+// validFulfilmentTypes are the ways an order reaches the customer
+var validFulfilmentTypes = map[string]bool{
+	"dine_in":  true,
+	"takeaway": true,
+	"delivery": true,
+}
+
+// applyFulfilment validates how an order is fulfilled and copies the details
+// belonging to its type onto the order. Orders with a delivery address default
+// to delivery, others to takeaway. Delivery orders without an address go to
+// the customer's default address.
+func applyFulfilment(tx *gorm.DB, order *models.Order, req CreateOrderRequest) error {
+	fulfilmentType := strings.ToLower(strings.TrimSpace(req.FulfilmentType))
+	if fulfilmentType == "" {
+		fulfilmentType = "takeaway"
+		if req.DeliveryAddressID != nil {
+			fulfilmentType = "delivery"
+		}
+	}
+	if !validFulfilmentTypes[fulfilmentType] {
+		return badRequest("Invalid fulfilment type %q. Must be one of: dine_in, takeaway, delivery", req.FulfilmentType)
+	}
+	order.FulfilmentType = fulfilmentType
+
+	tableNumber := strings.TrimSpace(req.TableNumber)
+	courier := strings.TrimSpace(req.Courier)
+	if tableNumber != "" && fulfilmentType != "dine_in" {
+		return badRequest("table_number is only used for dine-in orders")
+	}
+	if req.PickupTime != nil && fulfilmentType != "takeaway" {
+		return badRequest("pickup_time is only used for takeaway orders")
+	}
+	if fulfilmentType != "delivery" && (courier != "" || req.DeliveryAddressID != nil) {
+		return badRequest("courier and delivery_address_id are only used for delivery orders")
+	}
+
+	switch fulfilmentType {
+	case "dine_in":
+		if tableNumber == "" {
+			return badRequest("table_number is required for dine-in orders")
+		}
+		order.TableNumber = tableNumber
+
+	case "takeaway":
+		if req.PickupTime != nil && req.PickupTime.Before(time.Now().Add(-time.Minute)) {
+			return badRequest("pickup_time cannot be in the past")
+		}
+		order.PickupTime = req.PickupTime
+
+	case "delivery":
+		addressID := req.DeliveryAddressID
+		if addressID == nil {
+			var address models.CustomerAddress
+			err := tx.Where("customer_id = ? AND is_default = ?", order.CustomerID, true).First(&address).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return badRequest("delivery_address_id is required, customer %d has no default address", order.CustomerID)
+			}
+			if err != nil {
+				return err
+			}
+			addressID = &address.ID
+		}
+		if err := applyDeliveryAddress(tx, order, *addressID, req.DeliveryNotes); err != nil {
+			return err
+		}
+		if courier == "" {
+			courier = "in_house"
+		}
+		order.Courier = courier
+	}
+
+	return nil
+}
+
+// fulfilmentLabel describes how an order is fulfilled for receipts, e.g.
+// "Dine in, table 4", "Takeaway, pickup 18:30" or "Delivery"
+func fulfilmentLabel(order models.Order) string {
+	switch order.FulfilmentType {
+	case "dine_in":
+		return fmt.Sprintf("Dine in, table %s", order.TableNumber)
+	case "takeaway":
+		if order.PickupTime != nil {
+			pickup := *order.PickupTime
+			if location, err := shopLocation(""); err == nil {
+				pickup = pickup.In(location)
+			}
+			return "Takeaway, pickup " + pickup.Format("15:04")
+		}
+		return "Takeaway"
+	case "delivery":
+		if order.Courier != "" && order.Courier != "in_house" {
+			return "Delivery, " + order.Courier
+		}
+		return "Delivery"
+	}
+	return ""
+}
@@ -1,13 +1,16 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"main/models"
+	"main/receipt"
 	"main/utils"
 
 	"time"
@@ -23,15 +26,33 @@ type CreateInvoiceRequest struct {
 }
 
 type UpdatePaymentStatusRequest struct {
-	PaymentStatus string     `json:"payment_status" binding:"required"`
-	PaymentDate   *time.Time `json:"payment_date"`
-	Notes         string     `json:"notes"`
+	PaymentStatus string `json:"payment_status" binding:"required"` // pending or overdue
+	Notes         string `json:"notes"`
 }
 
 // Response structures
 type InvoiceResponse struct {
 	models.Invoice
 	Order *models.Order `json:"order,omitempty"`
+	Lines []InvoiceLine `json:"lines"`
+}
+
+// InvoiceLine is one order line as printed on the invoice, with its topping modifiers
+type InvoiceLine struct {
+	OrderItemID uint                  `json:"order_item_id"`
+	Description string                `json:"description"`
+	Quantity    int                   `json:"quantity"`
+	UnitPrice   models.Money          `json:"unit_price"`
+	TotalPrice  models.Money          `json:"total_price"`
+	Modifiers   []InvoiceLineModifier `json:"modifiers,omitempty"`
+}
+
+type InvoiceLineModifier struct {
+	Description string       `json:"description"` // e.g. "+ Extra Cheese x2" or "- No Onion"
+	Action      string       `json:"action"`
+	Quantity    int          `json:"quantity"`   // Portions per pizza
+	UnitPrice   models.Money `json:"unit_price"` // Per pizza
+	TotalPrice  models.Money `json:"total_price"`
 }
 
 func GetInvoices(w http.ResponseWriter, r *http.Request) {
@@ -128,7 +149,7 @@ func GetInvoiceByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var invoice models.Invoice
-	if err := db.Preload("Order").First(&invoice, invoiceID).Error; err != nil {
+	if err := preloadInvoice(db).First(&invoice, invoiceID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response := utils.APIResponse{
 				Success: false,
@@ -152,7 +173,7 @@ func GetInvoiceByID(w http.ResponseWriter, r *http.Request) {
 	response := utils.APIResponse{
 		Success: true,
 		Message: "Invoice retrieved successfully",
-		Data:    invoice,
+		Data:    newInvoiceResponse(invoice),
 	}
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
@@ -174,7 +195,7 @@ func GetInvoiceByOrderID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var invoice models.Invoice
-	if err := db.Preload("Order").Where("order_id = ?", orderID).First(&invoice).Error; err != nil {
+	if err := preloadInvoice(db).Where("order_id = ?", orderID).First(&invoice).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response := utils.APIResponse{
 				Success: false,
@@ -198,7 +219,7 @@ func GetInvoiceByOrderID(w http.ResponseWriter, r *http.Request) {
 	response := utils.APIResponse{
 		Success: true,
 		Message: "Invoice retrieved successfully",
-		Data:    invoice,
+		Data:    newInvoiceResponse(invoice),
 	}
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
@@ -228,91 +249,188 @@ func CreateInvoice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if order exists
-	var order models.Order
-	if err := db.First(&order, req.OrderID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			response := utils.APIResponse{
-				Success: false,
-				Message: "Order not found",
-				Data:    nil,
-			}
-			utils.SendJSONResponse(w, http.StatusNotFound, response)
-			return
+	invoice := models.Invoice{
+		OrderID:       req.OrderID,
+		InvoiceDate:   time.Now(),
+		PaymentStatus: "pending",
+		Notes:         req.Notes,
+	}
+
+	// The order is locked so two requests for the same order cannot both pass
+	// the existing invoice check. Tax, numbering and the insert share the
+	// transaction, so the invoice number is only used if the invoice is created.
+	err := db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, req.OrderID)
+		if err != nil {
+			return err
+		}
+		if order.OrderStatus == "cancelled" {
+			return conflict("Order %d is cancelled and cannot be invoiced", order.ID)
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Invoice{}).Where("order_id = ?", order.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return conflict("Invoice already exists for this order")
+		}
+
+		if err := tx.Where("order_id = ?", order.ID).Find(&order.OrderItems).Error; err != nil {
+			return err
 		}
 
-		log.Printf("Error fetching order: %v", err)
+		// Same tax computation as CreateOrder; the order total already includes tax
+		totals, err := applyOrderTax(tx, &order)
+		if err != nil {
+			return err
+		}
+		invoice.SubtotalAmount = totals.Subtotal
+		invoice.TaxAmount = totals.Tax
+		invoice.TotalAmount = totals.Total
+		invoice.Taxes = invoiceTaxes(totals.Breakdown)
+
+		invoiceNumber, err := nextDocumentNumber(tx, invoiceSeries, invoice.InvoiceDate)
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNumber = invoiceNumber
+		return tx.Create(&invoice).Error
+	})
+	if isUniqueViolation(err) {
+		// A deleted invoice still holds the order's unique index
+		err = conflict("Invoice already exists for this order")
+	}
+	if err != nil {
+		sendRequestError(w, err, "Failed to create invoice")
+		return
+	}
+
+	// Load the created invoice with order details
+	if err := preloadInvoice(db).First(&invoice, invoice.ID).Error; err != nil {
+		log.Printf("Error loading created invoice: %v", err)
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Invoice created successfully",
+		Data:    newInvoiceResponse(invoice),
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func UpdateInvoicePaymentStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("PUT /api/invoices/%s/payment-status called", id)
+
+	invoiceID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to validate order",
+			Message: "Invalid invoice ID",
 			Data:    nil,
 		}
-		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	// Check if invoice already exists for this order
-	var existingInvoice models.Invoice
-	if err := db.Where("order_id = ?", req.OrderID).First(&existingInvoice).Error; err == nil {
+	var req UpdatePaymentStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Invoice already exists for this order",
-			Data:    existingInvoice,
+			Message: "Invalid request body",
+			Data:    nil,
 		}
-		utils.SendJSONResponse(w, http.StatusConflict, response)
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	// Generate invoice number
-	invoiceNumber := generateInvoiceNumber()
-
-	// Calculate amounts (assuming order has total_amount field)
-	// You may need to adjust this based on your Order model structure
-	subtotal := order.TotalAmount // Adjust field name as needed
-	taxRate := 0.10               // 10% tax rate - make this configurable
-	taxAmount := subtotal * taxRate
-	totalAmount := subtotal + taxAmount
-
-	// Create invoice
-	invoice := models.Invoice{
-		OrderID:        req.OrderID,
-		InvoiceNumber:  invoiceNumber,
-		InvoiceDate:    time.Now(),
-		SubtotalAmount: subtotal,
-		TaxAmount:      taxAmount,
-		TotalAmount:    totalAmount,
-		PaymentStatus:  "pending",
-		Notes:          req.Notes,
-	}
-
-	if err := db.Create(&invoice).Error; err != nil {
-		log.Printf("Error creating invoice: %v", err)
+	// Paid, overpaid and partially paid follow from the recorded payments and
+	// cancelled from the order; only the overdue flag is set by hand
+	switch req.PaymentStatus {
+	case "pending", "overdue":
+	case "paid", "partially_paid", "overpaid":
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to create invoice",
+			Message: "Payment status follows the recorded payments. Record a payment with POST /api/invoices/{id}/payments",
 			Data:    nil,
 		}
-		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		utils.SendJSONResponse(w, http.StatusConflict, response)
+		return
+	case "cancelled":
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Cancel the order with POST /api/orders/{id}/cancel to void its invoice",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusConflict, response)
+		return
+	default:
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid payment status. Must be one of: pending, overdue",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	// Load the created invoice with order details
-	if err := db.Preload("Order").First(&invoice, invoice.ID).Error; err != nil {
-		log.Printf("Error loading created invoice: %v", err)
+	var invoice models.Invoice
+	err = db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockInvoice(tx, "id = ?", uint(invoiceID))
+		if err != nil {
+			return err
+		}
+		if locked == nil {
+			return notFound("Invoice not found")
+		}
+		invoice = *locked
+
+		if invoice.PaymentStatus != "pending" && invoice.PaymentStatus != "partially_paid" && invoice.PaymentStatus != "overdue" {
+			return conflict("Invoice %s is %s; only unpaid invoices can be marked overdue or pending", invoice.InvoiceNumber, invoice.PaymentStatus)
+		}
+
+		updates := map[string]interface{}{
+			"payment_status": req.PaymentStatus,
+			"updated_at":     time.Now(),
+		}
+		if req.Notes != "" {
+			updates["notes"] = req.Notes
+		}
+		if err := tx.Model(&invoice).Updates(updates).Error; err != nil {
+			return err
+		}
+		invoice.PaymentStatus = req.PaymentStatus
+
+		// Clearing the overdue flag puts back the status the payments give
+		if req.PaymentStatus == "pending" {
+			return refreshPaymentStatus(tx, &invoice)
+		}
+		return nil
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to update payment status")
+		return
+	}
+
+	// Reload invoice with updated data
+	if err := preloadInvoice(db).First(&invoice, invoiceID).Error; err != nil {
+		log.Printf("Error reloading invoice: %v", err)
 	}
 
 	response := utils.APIResponse{
 		Success: true,
-		Message: "Invoice created successfully",
-		Data:    invoice,
+		Message: "Payment status updated successfully",
+		Data:    newInvoiceResponse(invoice),
 	}
-	utils.SendJSONResponse(w, http.StatusCreated, response)
+	utils.SendJSONResponse(w, http.StatusOK, response)
 }
 
-func UpdateInvoicePaymentStatus(w http.ResponseWriter, r *http.Request) {
+func GetPrintableInvoice(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	log.Printf("PUT /api/invoices/%s/payment-status called", id)
+	log.Printf("GET /api/invoices/%s/print called", id)
 
 	invoiceID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
@@ -325,38 +443,29 @@ func UpdateInvoicePaymentStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req UpdatePaymentStatusRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	format := printFormat(r)
+	if format != "html" && format != "pdf" && format != "escpos" {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Invalid request body",
+			Message: "Invalid format. Must be one of: html, pdf, escpos",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	// Validate payment status
-	validStatuses := map[string]bool{
-		"pending":   true,
-		"paid":      true,
-		"overdue":   true,
-		"cancelled": true,
-	}
-
-	if !validStatuses[req.PaymentStatus] {
+	if opts := escposOptions(r); format == "escpos" && opts.Columns != 42 && opts.Columns != 48 {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Invalid payment status. Must be one of: pending, paid, overdue, cancelled",
+			Message: "Invalid columns. Must be 42 or 48",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	// Find the invoice
 	var invoice models.Invoice
-	if err := db.First(&invoice, invoiceID).Error; err != nil {
+	if err := preloadInvoice(db).First(&invoice, invoiceID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response := utils.APIResponse{
 				Success: false,
@@ -377,71 +486,181 @@ func UpdateInvoicePaymentStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update payment status
-	updates := map[string]interface{}{
-		"payment_status": req.PaymentStatus,
-		"updated_at":     time.Now(),
-	}
-
-	if req.PaymentDate != nil {
-		updates["payment_date"] = req.PaymentDate
-	} else if req.PaymentStatus == "paid" && invoice.PaymentDate == nil {
-		// Auto-set payment date when marking as paid
-		now := time.Now()
-		updates["payment_date"] = &now
-	}
+	printable := newReceipt(invoice)
 
-	if req.Notes != "" {
-		updates["notes"] = req.Notes
+	var buf bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	switch format {
+	case "pdf":
+		contentType = "application/pdf"
+		err = receipt.RenderPDF(&buf, printable)
+	case "escpos":
+		// Raw thermal printer stream, e.g. for printing from the till over USB
+		contentType = "application/octet-stream"
+		var data []byte
+		data, err = receipt.RenderESCPOS(printable, escposOptions(r))
+		buf.Write(data)
+	default:
+		err = receipt.RenderHTML(&buf, printable)
 	}
-
-	if err := db.Model(&invoice).Updates(updates).Error; err != nil {
-		log.Printf("Error updating invoice payment status: %v", err)
+	if err != nil {
+		log.Printf("Error rendering invoice %d as %s: %v", invoice.ID, format, err)
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to update payment status",
+			Message: "Failed to render invoice",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
 		return
 	}
 
-	// Reload invoice with updated data
-	if err := db.Preload("Order").First(&invoice, invoiceID).Error; err != nil {
-		log.Printf("Error reloading invoice: %v", err)
+	w.Header().Set("Content-Type", contentType)
+	switch format {
+	case "pdf":
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", invoice.InvoiceNumber+".pdf"))
+	case "escpos":
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", invoice.InvoiceNumber+".bin"))
 	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
 
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Payment status updated successfully",
-		Data:    invoice,
+// Helper function to load an invoice with its order lines and their modifiers
+func preloadInvoice(query *gorm.DB) *gorm.DB {
+	query = query.Preload("Order").
+		Preload("Taxes").
+		Preload("Payments", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Preload("CreditNotes.Lines").
+		Preload("CreditNotes.Taxes")
+	return preloadOrderItems(query, "Order.")
+}
+
+// Helper function to build the invoice line breakdown from the order items
+func newInvoiceResponse(invoice models.Invoice) InvoiceResponse {
+	resp := InvoiceResponse{
+		Invoice: invoice,
+		Order:   &invoice.Order,
+		Lines:   make([]InvoiceLine, 0, len(invoice.Order.OrderItems)),
+	}
+
+	for _, orderItem := range invoice.Order.OrderItems {
+		line := InvoiceLine{
+			OrderItemID: orderItem.ID,
+			Description: orderItem.Description,
+			Quantity:    orderItem.Quantity,
+			UnitPrice:   orderItem.UnitPrice,
+			TotalPrice:  orderItem.TotalPrice,
+		}
+		if line.Description == "" {
+			line.Description = orderItem.Item.Name
+		}
+
+		for _, topping := range orderItem.Toppings {
+			modifier := InvoiceLineModifier{
+				Action:    topping.Action,
+				Quantity:  topping.Quantity,
+				UnitPrice: topping.UnitPrice,
+			}
+			// Cannot overflow: the line's stored total already holds it
+			modifier.TotalPrice, _ = topping.TotalPrice.Times(orderItem.Quantity)
+			if topping.Action == "remove" {
+				modifier.Description = "- No " + topping.Name
+			} else {
+				modifier.Description = "+ " + topping.Name
+				if topping.Quantity > 1 {
+					modifier.Description += fmt.Sprintf(" x%d", topping.Quantity)
+				}
+			}
+			line.Modifiers = append(line.Modifiers, modifier)
+		}
+
+		resp.Lines = append(resp.Lines, line)
 	}
-	utils.SendJSONResponse(w, http.StatusOK, response)
+
+	return resp
 }
 
-// func GetPrintableInvoice(w http.ResponseWriter, r *http.Request) {
-// 	vars := mux.Vars(r)
-// 	id := vars["id"]
-// 	log.Printf("GET /api/invoices/%s/print called", id)
-
-// 	// TODO: Generate printable invoice format (HTML/PDF)
-// 	response := utils.APIResponse{
-// 		Success: true,
-// 		Message: "Printable invoice retrieved successfully",
-// 		Data:    nil, // Placeholder - should return HTML or PDF data
-// 	}
-// 	utils.SendJSONResponse(w, http.StatusOK, response)
-// }
-
-// Helper function to generate invoice number
-func generateInvoiceNumber() string {
-	// Generate invoice number with format: INV-YYYY-XXXXXX
-	now := time.Now()
-	year := now.Year()
-
-	// Get count of invoices this year for sequential numbering
-	var count int64
-	db.Model(&models.Invoice{}).Where("EXTRACT(YEAR FROM invoice_date) = ?", year).Count(&count)
-
-	return fmt.Sprintf("INV-%d-%06d", year, count+1)
+// Helper function to pick the print format: the format query parameter wins,
+// otherwise an Accept header asking for PDF, otherwise HTML
+func printFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.Contains(r.Header.Get("Accept"), "application/pdf") {
+		return "pdf"
+	}
+	return "html"
+}
+
+// Helper function to build the printable receipt for an invoice
+func newReceipt(invoice models.Invoice) receipt.Receipt {
+	printable := receipt.Receipt{
+		Config:        receipt.ConfigFromEnv(),
+		InvoiceNumber: invoice.InvoiceNumber,
+		InvoiceDate:   invoice.InvoiceDate,
+		OrderID:       invoice.OrderID,
+		Subtotal:      invoice.SubtotalAmount,
+		Tax:           invoice.TaxAmount,
+		Total:         invoice.TotalAmount,
+		PaymentStatus: invoice.PaymentStatus,
+		Notes:         invoice.Notes,
+	}
+
+	for _, tax := range invoice.Taxes {
+		label := fmt.Sprintf("%s %s%%", tax.Name, strconv.FormatFloat(tax.Rate, 'f', -1, 64))
+		if tax.Inclusive {
+			label += " (incl.)"
+		}
+		printable.Taxes = append(printable.Taxes, receipt.TaxLine{Label: label, Amount: tax.TaxAmount})
+	}
+
+	for _, payment := range invoice.Payments {
+		if payment.Reversed {
+			continue
+		}
+		switch payment.Method {
+		case "cash_rounding":
+			// Printed first, as the change to the total: a balance rounded down shows as negative
+			printable.Payments = append([]receipt.PaymentLine{{Label: "Rounding", Amount: -payment.Amount}}, printable.Payments...)
+		case "cash":
+			amount := payment.Amount
+			if payment.Tendered > 0 {
+				amount = payment.Tendered
+			}
+			printable.Payments = append(printable.Payments, receipt.PaymentLine{Label: "Cash", Amount: amount})
+			printable.Change += payment.Change
+		default:
+			label := strings.ToUpper(payment.Method[:1]) + strings.ReplaceAll(payment.Method[1:], "_", " ")
+			printable.Payments = append(printable.Payments, receipt.PaymentLine{Label: label, Amount: payment.Amount})
+		}
+	}
+
+	var customer models.Customer
+	if err := db.Unscoped().First(&customer, invoice.Order.CustomerID).Error; err == nil {
+		printable.CustomerName = customer.Name
+		printable.CustomerTelNo = customer.TelNo
+	}
+	printable.Fulfilment = fulfilmentLabel(invoice.Order)
+	printable.DeliverTo = invoice.Order.DeliveryAddress
+	if printable.DeliverTo != "" {
+		printable.DeliveryNotes = invoice.Order.DeliveryNotes
+	}
+
+	for _, line := range newInvoiceResponse(invoice).Lines {
+		printLine := receipt.Line{
+			Description: line.Description,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			Total:       line.TotalPrice,
+		}
+		for _, modifier := range line.Modifiers {
+			printLine.Modifiers = append(printLine.Modifiers, receipt.Modifier{
+				Description: modifier.Description,
+				Total:       modifier.TotalPrice,
+			})
+		}
+		printable.Lines = append(printable.Lines, printLine)
+	}
+
+	return printable
 }
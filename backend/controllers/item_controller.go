@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 
@@ -13,6 +17,83 @@ import (
 	"gorm.io/gorm"
 )
 
+// Request structures. Fields are pointers so updates only touch what was sent.
+type ItemRequest struct {
+	Name       *string       `json:"name"`
+	Type       *string       `json:"type"`
+	UnitPrice  *models.Money `json:"unit_price"`
+	TaxClassID *uint         `json:"tax_class_id"` // 0 clears the override
+	IsActive   *bool         `json:"is_active"`
+}
+
+// RestoreItemRequest optionally reprices an item as it is restored
+type RestoreItemRequest struct {
+	UnitPrice *models.Money `json:"unit_price"`
+}
+
+// RestoreToppingRequest optionally reprices a topping as it is restored
+type RestoreToppingRequest struct {
+	Price *models.Money `json:"price"`
+}
+
+type PizzaRequest struct {
+	ItemID   *uint         `json:"item_id"`
+	Name     *string       `json:"name"`
+	Size     *string       `json:"size"`
+	BaseType *string       `json:"base_type"`
+	Price    *models.Money `json:"price"`
+	IsActive *bool         `json:"is_active"`
+}
+
+type ToppingRequest struct {
+	ItemID    *uint         `json:"item_id"` // 0 clears the parent item
+	ToppingID *uint         `json:"topping_id"`
+	Name      *string       `json:"name"`
+	Price     *models.Money `json:"price"`
+	IsActive  *bool         `json:"is_active"`
+}
+
+type BeverageRequest struct {
+	ItemID     *uint         `json:"item_id"`
+	BeverageID *uint         `json:"beverage_id"`
+	Name       *string       `json:"name"`
+	Size       *string       `json:"size"`
+	Price      *models.Money `json:"price"`
+	IsActive   *bool         `json:"is_active"`
+}
+
+// CatalogItemResponse is an item with its variants and the sizes and base types they come in
+type CatalogItemResponse struct {
+	models.Item
+	Sizes     []string `json:"sizes"`
+	BaseTypes []string `json:"base_types"`
+}
+
+var validItemTypes = []string{"pizza", "topping", "beverage"}
+
+// pizzaSizes returns PIZZA_SIZES, the comma separated pizza sizes the menu
+// allows; when it is not set any size is accepted
+func pizzaSizes() []string {
+	return envList("PIZZA_SIZES")
+}
+
+// pizzaBaseTypes returns PIZZA_BASE_TYPES, the comma separated base types the
+// menu allows; when it is not set any base type is accepted
+func pizzaBaseTypes() []string {
+	return envList("PIZZA_BASE_TYPES")
+}
+
+// envList splits a comma separated environment variable, dropping empty entries
+func envList(name string) []string {
+	values := []string{}
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 var db *gorm.DB
 
 func SetDB(database *gorm.DB) {
@@ -134,104 +215,68 @@ func GetItemsByType(w http.ResponseWriter, r *http.Request) {
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
 
-func CreateItem(w http.ResponseWriter, r *http.Request) {
-	log.Println("POST /api/items called")
-
-	// TODO: Parse request body and create item
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Item created successfully",
-		Data:    nil, // Placeholder
-	}
-	utils.SendJSONResponse(w, http.StatusCreated, response)
-}
-
-func UpdateItem(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	log.Printf("PUT /api/items/%s called", id)
-
-	// TODO: Parse request body and update item
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Item updated successfully",
-		Data:    nil, // Placeholder
-	}
-	utils.SendJSONResponse(w, http.StatusOK, response)
-}
-
-func DeleteItem(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	log.Printf("DELETE /api/items/%s called", id)
-
-	// TODO: Implement soft delete (set is_active = false)
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Item deleted successfully",
-	}
-	utils.SendJSONResponse(w, http.StatusOK, response)
-}
-
-// Pizza specific endpoints
-func GetPizzas(w http.ResponseWriter, r *http.Request) {
-	log.Println("GET /api/pizzas called")
-
-	var pizzas []models.Pizza
+// GetCatalog returns every active item with its active pizza, beverage and topping variants
+func GetCatalog(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/catalog called")
 
-	// Using GORM to fetch all active pizzas
-	if err := db.Where("is_active = ?", true).Find(&pizzas).Error; err != nil {
-		log.Printf("Error fetching pizzas: %v", err)
+	var items []models.Item
+	if err := preloadVariants(db).Where("is_active = ?", true).Order("type, name").Find(&items).Error; err != nil {
+		log.Printf("Error fetching catalog: %v", err)
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to retrieve pizzas",
+			Message: "Failed to retrieve catalog",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
 		return
 	}
 
+	catalog := make([]CatalogItemResponse, 0, len(items))
+	for _, item := range items {
+		catalog = append(catalog, newCatalogItemResponse(item))
+	}
+
 	response := utils.APIResponse{
 		Success: true,
-		Message: "Pizzas retrieved successfully",
-		Data:    pizzas,
+		Message: "Catalog retrieved successfully",
+		Data:    catalog,
 	}
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
 
-// Pizza by ID endpoint
-func GetPizzaByID(w http.ResponseWriter, r *http.Request) {
+// GetItemVariants returns one item together with all its sizes and base types
+func GetItemVariants(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	log.Printf("GET /api/pizzas/%s called", id)
+	log.Printf("GET /api/items/%s/variants called", id)
 
-	pizzaID, err := strconv.ParseUint(id, 10, 32)
+	itemID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Invalid pizza ID",
+			Message: "Invalid item ID",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	var pizza models.Pizza
-	if err := db.Where("id = ? AND is_active = ?", uint(pizzaID), true).First(&pizza).Error; err != nil {
+	var item models.Item
+	if err := preloadVariants(db).Where("id = ? AND is_active = ?", uint(itemID), true).First(&item).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response := utils.APIResponse{
 				Success: false,
-				Message: "Pizza not found",
+				Message: "Item not found",
 				Data:    nil,
 			}
 			utils.SendJSONResponse(w, http.StatusNotFound, response)
 			return
 		}
 
-		log.Printf("Error fetching pizza: %v", err)
+		log.Printf("Error fetching item variants: %v", err)
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to retrieve pizza",
+			Message: "Failed to retrieve item",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
@@ -240,129 +285,55 @@ func GetPizzaByID(w http.ResponseWriter, r *http.Request) {
 
 	response := utils.APIResponse{
 		Success: true,
-		Message: "Pizza retrieved successfully",
-		Data:    pizza,
-	}
-	log.Printf("Pizza retrieved: %+v", response.Data)
-	utils.SendJSONResponse(w, http.StatusOK, response)
-}
-
-func CreatePizza(w http.ResponseWriter, r *http.Request) {
-	log.Println("POST /api/pizzas called")
-
-	// TODO: Parse request body and create pizza
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Pizza created successfully",
-		Data:    nil, // Placeholder
-	}
-	utils.SendJSONResponse(w, http.StatusCreated, response)
-}
-
-func UpdatePizza(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	log.Printf("PUT /api/pizzas/%s called", id)
-
-	// TODO: Parse request body and update pizza
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Pizza updated successfully",
-		Data:    nil, // Placeholder
-	}
-	utils.SendJSONResponse(w, http.StatusOK, response)
-}
-
-func DeletePizza(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	log.Printf("DELETE /api/pizzas/%s called", id)
-
-	// TODO: Implement soft delete (set is_active = false)
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Pizza deleted successfully",
+		Message: "Item retrieved successfully",
+		Data:    newCatalogItemResponse(item),
 	}
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
 
-// Topping specific endpoints
-func GetToppings(w http.ResponseWriter, r *http.Request) {
-	log.Println("GET /api/toppings called")
-
-	var toppings []models.Topping
+func CreateItem(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /api/items called")
 
-	// Using GORM to fetch all active toppings
-	if err := db.Where("is_active = ?", true).Find(&toppings).Error; err != nil {
-		log.Printf("Error fetching toppings: %v", err)
+	var req ItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to retrieve toppings",
+			Message: "Invalid request body",
 			Data:    nil,
 		}
-		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Toppings retrieved successfully",
-		Data:    toppings,
+	if msg := req.validate(true); msg != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
 	}
-	utils.SendJSONResponse(w, http.StatusOK, response)
-}
-
-func CreateTopping(w http.ResponseWriter, r *http.Request) {
-	log.Println("POST /api/toppings called")
-
-	// TODO: Parse request body and create topping
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Topping created successfully",
-		Data:    nil, // Placeholder
+	if err := checkTaxClass(req.TaxClassID); err != nil {
+		sendRequestError(w, err, "Failed to create item")
+		return
 	}
-	utils.SendJSONResponse(w, http.StatusCreated, response)
-}
-
-func UpdateTopping(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	log.Printf("PUT /api/toppings/%s called", id)
 
-	// TODO: Parse request body and update pizza
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Topping updated successfully",
-		Data:    nil, // Placeholder
+	item := models.Item{
+		Name:      strings.TrimSpace(*req.Name),
+		Type:      strings.ToLower(*req.Type),
+		UnitPrice: *req.UnitPrice,
+		IsActive:  true,
 	}
-	utils.SendJSONResponse(w, http.StatusOK, response)
-}
-
-func DeleteTopping(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	log.Printf("DELETE /api/toppings/%s called", id)
-
-	// TODO: Implement soft delete (set is_active = false)
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Pizza deleted successfully",
+	if req.TaxClassID != nil && *req.TaxClassID != 0 {
+		item.TaxClassID = req.TaxClassID
 	}
-	utils.SendJSONResponse(w, http.StatusOK, response)
-}
-
-// Beverage specific endpoints
-func GetBeverages(w http.ResponseWriter, r *http.Request) {
-	log.Println("GET /api/beverages called")
 
-	var beverages []models.Beverage
-
-	// Using GORM to fetch all active beverages
-	if err := db.Where("is_active = ?", true).Find(&beverages).Error; err != nil {
-		log.Printf("Error fetching beverages: %v", err)
+	if err := db.Create(&item).Error; err != nil {
+		log.Printf("Error creating item: %v", err)
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to retrieve beverages",
+			Message: "Failed to create item",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
@@ -371,113 +342,1020 @@ func GetBeverages(w http.ResponseWriter, r *http.Request) {
 
 	response := utils.APIResponse{
 		Success: true,
-		Message: "Beverages retrieved successfully",
-		Data:    beverages,
+		Message: "Item created successfully",
+		Data:    item,
 	}
-	utils.SendJSONResponse(w, http.StatusOK, response)
+	utils.SendJSONResponse(w, http.StatusCreated, response)
 }
 
-func GetToppingByID(w http.ResponseWriter, r *http.Request) {
+func UpdateItem(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	log.Printf("GET /api/toppings/%s called", id)
+	log.Printf("PUT /api/items/%s called", id)
 
-	toppingID, err := strconv.ParseUint(id, 10, 32)
+	itemID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Invalid topping ID",
+			Message: "Invalid item ID",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	var topping models.Topping
-	if err := db.Where("id = ? AND is_active = ?", uint(toppingID), true).First(&topping).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			response := utils.APIResponse{
-				Success: false,
-				Message: "Topping not found",
-				Data:    nil,
-			}
-			utils.SendJSONResponse(w, http.StatusNotFound, response)
-			return
+	var req ItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
 		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
 
-		log.Printf("Error fetching topping: %v", err)
+	if msg := req.validate(false); msg != "" {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to retrieve topping",
+			Message: msg,
 			Data:    nil,
 		}
-		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
-
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Topping retrieved successfully",
-		Data:    topping,
+	if err := checkTaxClass(req.TaxClassID); err != nil {
+		sendRequestError(w, err, "Failed to update item")
+		return
 	}
-	utils.SendJSONResponse(w, http.StatusOK, response)
-}
 
-// Beverage by ID endpoint
-func GetBeverageByID(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-	log.Printf("GET /api/beverages/%s called", id)
-
-	beverageID, err := strconv.ParseUint(id, 10, 32)
-	if err != nil {
+	updates := req.updates()
+	if len(updates) == 0 {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Invalid beverage ID",
+			Message: "No fields to update",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	var beverage models.Beverage
-	if err := db.Where("id = ? AND is_active = ?", uint(beverageID), true).First(&beverage).Error; err != nil {
+	var item models.Item
+	if err := db.First(&item, uint(itemID)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response := utils.APIResponse{
 				Success: false,
-				Message: "Beverage not found",
+				Message: "Item not found",
 				Data:    nil,
 			}
 			utils.SendJSONResponse(w, http.StatusNotFound, response)
 			return
 		}
 
-		log.Printf("Error fetching beverage: %v", err)
+		log.Printf("Error fetching item: %v", err)
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to retrieve beverage",
+			Message: "Failed to update item",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
 		return
 	}
 
-	response := utils.APIResponse{
-		Success: true,
-		Message: "Beverage retrieved successfully",
-		Data:    beverage,
-	}
-	utils.SendJSONResponse(w, http.StatusOK, response)
-}
+	// Variants belong to an item of their own type
+	if newType, ok := updates["type"].(string); ok && newType != item.Type {
+		variants, err := countItemVariants(item.ID)
+		if err != nil {
+			sendRequestError(w, err, "Failed to update item")
+			return
+		}
+		if variants > 0 {
+			sendRequestError(w, conflict("Item %d has %d %s variant(s); its type cannot change to %s", item.ID, variants, item.Type, newType), "")
+			return
+		}
+	}
+
+	if err := db.Model(&item).Updates(updates).Error; err != nil {
+		log.Printf("Error updating item: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to update item",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	// Reload item with updated data
+	if err := db.First(&item, uint(itemID)).Error; err != nil {
+		log.Printf("Error reloading item: %v", err)
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Item updated successfully",
+		Data:    item,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func DeleteItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("DELETE /api/items/%s called", id)
+
+	itemID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid item ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if err := softDeleteRecord(&models.Item{}, uint(itemID)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Item not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error deleting item: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to delete item",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Item deleted successfully",
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func RestoreItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/items/%s/restore called", id)
+
+	itemID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid item ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req RestoreItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var item models.Item
+	if err := restorePricedRecord(&item, uint(itemID), "Item", "unit_price", req.UnitPrice); err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendRequestError(w, err, "")
+			return
+		}
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Deleted item not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error restoring item: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to restore item",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Item restored successfully",
+		Data:    item,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// Pizza specific endpoints
+func GetPizzas(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/pizzas called")
+
+	var pizzas []models.Pizza
+
+	// Using GORM to fetch all active pizzas
+	if err := db.Where("is_active = ?", true).Find(&pizzas).Error; err != nil {
+		log.Printf("Error fetching pizzas: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve pizzas",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Pizzas retrieved successfully",
+		Data:    pizzas,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// Pizza by ID endpoint
+func GetPizzaByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/pizzas/%s called", id)
+
+	pizzaID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid pizza ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var pizza models.Pizza
+	if err := db.Where("id = ? AND is_active = ?", uint(pizzaID), true).First(&pizza).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Pizza not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching pizza: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve pizza",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Pizza retrieved successfully",
+		Data:    pizza,
+	}
+	log.Printf("Pizza retrieved: %+v", response.Data)
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func CreatePizza(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /api/pizzas called")
+
+	var req PizzaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if msg := req.validate(true); msg != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if err := checkParentItem(*req.ItemID, "pizza"); err != nil {
+		sendRequestError(w, err, "Failed to create pizza")
+		return
+	}
+
+	pizza := models.Pizza{
+		ItemID:   *req.ItemID,
+		Name:     strings.TrimSpace(*req.Name),
+		Size:     strings.TrimSpace(*req.Size),
+		BaseType: strings.TrimSpace(*req.BaseType),
+		Price:    *req.Price,
+		IsActive: true,
+	}
+
+	if err := db.Create(&pizza).Error; err != nil {
+		log.Printf("Error creating pizza: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to create pizza",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Pizza created successfully",
+		Data:    pizza,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func UpdatePizza(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("PUT /api/pizzas/%s called", id)
+
+	pizzaID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid pizza ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req PizzaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if msg := req.validate(false); msg != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	updates := req.updates()
+	if len(updates) == 0 {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "No fields to update",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if req.ItemID != nil {
+		if err := checkParentItem(*req.ItemID, "pizza"); err != nil {
+			sendRequestError(w, err, "Failed to update pizza")
+			return
+		}
+	}
+
+	var pizza models.Pizza
+	if err := db.First(&pizza, uint(pizzaID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Pizza not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching pizza: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to update pizza",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	if err := db.Model(&pizza).Updates(updates).Error; err != nil {
+		log.Printf("Error updating pizza: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to update pizza",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	// Reload pizza with updated data
+	if err := db.First(&pizza, uint(pizzaID)).Error; err != nil {
+		log.Printf("Error reloading pizza: %v", err)
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Pizza updated successfully",
+		Data:    pizza,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func DeletePizza(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("DELETE /api/pizzas/%s called", id)
+
+	pizzaID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid pizza ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if err := softDeleteRecord(&models.Pizza{}, uint(pizzaID)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Pizza not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error deleting pizza: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to delete pizza",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Pizza deleted successfully",
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func RestorePizza(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/pizzas/%s/restore called", id)
+
+	pizzaID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid pizza ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var pizza models.Pizza
+	if err := restoreRecord(&pizza, uint(pizzaID)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Deleted pizza not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error restoring pizza: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to restore pizza",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Pizza restored successfully",
+		Data:    pizza,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// Topping specific endpoints
+func GetToppings(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/toppings called")
+
+	var toppings []models.Topping
+
+	// Using GORM to fetch all active toppings
+	if err := db.Where("is_active = ?", true).Find(&toppings).Error; err != nil {
+		log.Printf("Error fetching toppings: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve toppings",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Toppings retrieved successfully",
+		Data:    toppings,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func CreateTopping(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /api/toppings called")
+
+	var req ToppingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if msg := req.validate(true); msg != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if req.ItemID != nil && *req.ItemID != 0 {
+		if err := checkParentItem(*req.ItemID, "topping"); err != nil {
+			sendRequestError(w, err, "Failed to create topping")
+			return
+		}
+	}
+
+	topping := models.Topping{
+		Name:     strings.TrimSpace(*req.Name),
+		Price:    *req.Price,
+		IsActive: true,
+	}
+	if req.ItemID != nil && *req.ItemID != 0 {
+		topping.ItemID = req.ItemID
+	}
+	if req.ToppingID != nil {
+		topping.ToppingID = *req.ToppingID
+	}
+
+	if err := db.Create(&topping).Error; err != nil {
+		log.Printf("Error creating topping: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to create topping",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Topping created successfully",
+		Data:    topping,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func UpdateTopping(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("PUT /api/toppings/%s called", id)
+
+	toppingID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid topping ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req ToppingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if msg := req.validate(false); msg != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	updates := req.updates()
+	if len(updates) == 0 {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "No fields to update",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if req.ItemID != nil && *req.ItemID != 0 {
+		if err := checkParentItem(*req.ItemID, "topping"); err != nil {
+			sendRequestError(w, err, "Failed to update topping")
+			return
+		}
+	}
+
+	var topping models.Topping
+	if err := db.First(&topping, uint(toppingID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Topping not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching topping: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to update topping",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	if err := db.Model(&topping).Updates(updates).Error; err != nil {
+		log.Printf("Error updating topping: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to update topping",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	// Reload topping with updated data
+	if err := db.First(&topping, uint(toppingID)).Error; err != nil {
+		log.Printf("Error reloading topping: %v", err)
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Topping updated successfully",
+		Data:    topping,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func DeleteTopping(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("DELETE /api/toppings/%s called", id)
+
+	toppingID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid topping ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if err := softDeleteRecord(&models.Topping{}, uint(toppingID)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Topping not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error deleting topping: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to delete topping",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Topping deleted successfully",
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func RestoreTopping(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/toppings/%s/restore called", id)
+
+	toppingID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid topping ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req RestoreToppingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var topping models.Topping
+	if err := restorePricedRecord(&topping, uint(toppingID), "Topping", "price", req.Price); err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			sendRequestError(w, err, "")
+			return
+		}
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Deleted topping not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error restoring topping: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to restore topping",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Topping restored successfully",
+		Data:    topping,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// Beverage specific endpoints
+func GetBeverages(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/beverages called")
+
+	var beverages []models.Beverage
+
+	// Using GORM to fetch all active beverages
+	if err := db.Where("is_active = ?", true).Find(&beverages).Error; err != nil {
+		log.Printf("Error fetching beverages: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve beverages",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Beverages retrieved successfully",
+		Data:    beverages,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetToppingByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/toppings/%s called", id)
+
+	toppingID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid topping ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var topping models.Topping
+	if err := db.Where("id = ? AND is_active = ?", uint(toppingID), true).First(&topping).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Topping not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching topping: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve topping",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Topping retrieved successfully",
+		Data:    topping,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// Beverage by ID endpoint
+func GetBeverageByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/beverages/%s called", id)
+
+	beverageID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid beverage ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var beverage models.Beverage
+	if err := db.Where("id = ? AND is_active = ?", uint(beverageID), true).First(&beverage).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Beverage not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching beverage: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve beverage",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Beverage retrieved successfully",
+		Data:    beverage,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func CreateBeverage(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /api/beverages called")
+
+	var req BeverageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if msg := req.validate(true); msg != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if err := checkParentItem(*req.ItemID, "beverage"); err != nil {
+		sendRequestError(w, err, "Failed to create beverage")
+		return
+	}
 
-func CreateBeverage(w http.ResponseWriter, r *http.Request) {
-	log.Println("POST /api/beverages called")
+	beverage := models.Beverage{
+		ItemID:   *req.ItemID,
+		Name:     strings.TrimSpace(*req.Name),
+		Size:     strings.TrimSpace(*req.Size),
+		Price:    *req.Price,
+		IsActive: true,
+	}
+	if req.BeverageID != nil {
+		beverage.BeverageID = *req.BeverageID
+	}
+
+	if err := db.Create(&beverage).Error; err != nil {
+		log.Printf("Error creating beverage: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to create beverage",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
 
-	// TODO: Parse request body and create beverage
 	response := utils.APIResponse{
 		Success: true,
 		Message: "Beverage created successfully",
-		Data:    nil, // Placeholder
+		Data:    beverage,
 	}
 	utils.SendJSONResponse(w, http.StatusCreated, response)
 }
@@ -487,11 +1365,98 @@ func UpdateBeverage(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 	log.Printf("PUT /api/beverages/%s called", id)
 
-	// TODO: Parse request body and update pizza
+	beverageID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid beverage ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req BeverageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if msg := req.validate(false); msg != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	updates := req.updates()
+	if len(updates) == 0 {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "No fields to update",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if req.ItemID != nil {
+		if err := checkParentItem(*req.ItemID, "beverage"); err != nil {
+			sendRequestError(w, err, "Failed to update beverage")
+			return
+		}
+	}
+
+	var beverage models.Beverage
+	if err := db.First(&beverage, uint(beverageID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Beverage not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching beverage: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to update beverage",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	if err := db.Model(&beverage).Updates(updates).Error; err != nil {
+		log.Printf("Error updating beverage: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to update beverage",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	// Reload beverage with updated data
+	if err := db.First(&beverage, uint(beverageID)).Error; err != nil {
+		log.Printf("Error reloading beverage: %v", err)
+	}
+
 	response := utils.APIResponse{
 		Success: true,
 		Message: "Beverage updated successfully",
-		Data:    nil, // Placeholder
+		Data:    beverage,
 	}
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
@@ -501,10 +1466,400 @@ func DeleteBeverage(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 	log.Printf("DELETE /api/beverages/%s called", id)
 
-	// TODO: Implement soft delete (set is_active = false)
+	beverageID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid beverage ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if err := softDeleteRecord(&models.Beverage{}, uint(beverageID)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Beverage not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error deleting beverage: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to delete beverage",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
 	response := utils.APIResponse{
 		Success: true,
 		Message: "Beverage deleted successfully",
 	}
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
+
+func RestoreBeverage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/beverages/%s/restore called", id)
+
+	beverageID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid beverage ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var beverage models.Beverage
+	if err := restoreRecord(&beverage, uint(beverageID)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Deleted beverage not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error restoring beverage: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to restore beverage",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Beverage restored successfully",
+		Data:    beverage,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func (req ItemRequest) validate(create bool) string {
+	if create && (req.Name == nil || req.Type == nil || req.UnitPrice == nil) {
+		return "Name, type and unit_price are required"
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		return "Name cannot be empty"
+	}
+	if req.Type != nil && !containsFold(validItemTypes, *req.Type) {
+		return "Invalid item type. Valid types: " + strings.Join(validItemTypes, ", ")
+	}
+	if req.UnitPrice != nil && *req.UnitPrice < 0 {
+		return "Unit price cannot be negative"
+	}
+	return ""
+}
+
+func (req ItemRequest) updates() map[string]interface{} {
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Type != nil {
+		updates["type"] = strings.ToLower(*req.Type)
+	}
+	if req.UnitPrice != nil {
+		updates["unit_price"] = *req.UnitPrice
+	}
+	if req.TaxClassID != nil {
+		if *req.TaxClassID == 0 {
+			updates["tax_class_id"] = nil
+		} else {
+			updates["tax_class_id"] = *req.TaxClassID
+		}
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	return updates
+}
+
+func (req PizzaRequest) validate(create bool) string {
+	if create && (req.ItemID == nil || req.Name == nil || req.Size == nil || req.BaseType == nil || req.Price == nil) {
+		return "Item ID, name, size, base_type and price are required"
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		return "Name cannot be empty"
+	}
+	if req.Size != nil && strings.TrimSpace(*req.Size) == "" {
+		return "Size cannot be empty"
+	}
+	if sizes := pizzaSizes(); req.Size != nil && len(sizes) > 0 && !containsFold(sizes, strings.TrimSpace(*req.Size)) {
+		return "Invalid pizza size. Valid sizes: " + strings.Join(sizes, ", ")
+	}
+	if req.BaseType != nil && strings.TrimSpace(*req.BaseType) == "" {
+		return "Base type cannot be empty"
+	}
+	if baseTypes := pizzaBaseTypes(); req.BaseType != nil && len(baseTypes) > 0 && !containsFold(baseTypes, strings.TrimSpace(*req.BaseType)) {
+		return "Invalid base type. Valid base types: " + strings.Join(baseTypes, ", ")
+	}
+	if req.Price != nil && *req.Price < 0 {
+		return "Price cannot be negative"
+	}
+	return ""
+}
+
+func (req PizzaRequest) updates() map[string]interface{} {
+	updates := map[string]interface{}{}
+	if req.ItemID != nil {
+		updates["item_id"] = *req.ItemID
+	}
+	if req.Name != nil {
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Size != nil {
+		updates["size"] = strings.TrimSpace(*req.Size)
+	}
+	if req.BaseType != nil {
+		updates["base_type"] = strings.TrimSpace(*req.BaseType)
+	}
+	if req.Price != nil {
+		updates["price"] = *req.Price
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	return updates
+}
+
+func (req ToppingRequest) validate(create bool) string {
+	if create && (req.Name == nil || req.Price == nil) {
+		return "Name and price are required"
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		return "Name cannot be empty"
+	}
+	if req.Price != nil && *req.Price < 0 {
+		return "Price cannot be negative"
+	}
+	return ""
+}
+
+func (req ToppingRequest) updates() map[string]interface{} {
+	updates := map[string]interface{}{}
+	if req.ItemID != nil {
+		if *req.ItemID == 0 {
+			updates["item_id"] = nil
+		} else {
+			updates["item_id"] = *req.ItemID
+		}
+	}
+	if req.ToppingID != nil {
+		updates["topping_id"] = *req.ToppingID
+	}
+	if req.Name != nil {
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Price != nil {
+		updates["price"] = *req.Price
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	return updates
+}
+
+func (req BeverageRequest) validate(create bool) string {
+	if create && (req.ItemID == nil || req.Name == nil || req.Size == nil || req.Price == nil) {
+		return "Item ID, name, size and price are required"
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		return "Name cannot be empty"
+	}
+	if req.Size != nil && strings.TrimSpace(*req.Size) == "" {
+		return "Size cannot be empty"
+	}
+	if req.Price != nil && *req.Price < 0 {
+		return "Price cannot be negative"
+	}
+	return ""
+}
+
+func (req BeverageRequest) updates() map[string]interface{} {
+	updates := map[string]interface{}{}
+	if req.ItemID != nil {
+		updates["item_id"] = *req.ItemID
+	}
+	if req.BeverageID != nil {
+		updates["beverage_id"] = *req.BeverageID
+	}
+	if req.Name != nil {
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Size != nil {
+		updates["size"] = strings.TrimSpace(*req.Size)
+	}
+	if req.Price != nil {
+		updates["price"] = *req.Price
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	return updates
+}
+
+// Helper function to check that a tax class override names an existing class;
+// nil and 0 (clearing the override) need no check
+func checkTaxClass(taxClassID *uint) error {
+	if taxClassID == nil || *taxClassID == 0 {
+		return nil
+	}
+	var class models.TaxClass
+	if err := db.First(&class, *taxClassID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return badRequest("Tax class with ID %d not found", *taxClassID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Helper function to check that a pizza, beverage or topping points at an item of the right type
+func checkParentItem(itemID uint, itemType string) error {
+	var item models.Item
+	if err := db.Where("id = ? AND type = ?", itemID, itemType).First(&item).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return badRequest("Item with ID %d is not a %s item", itemID, itemType)
+		}
+		return err
+	}
+	return nil
+}
+
+// Helper function to count the pizzas, beverages and toppings of an item,
+// deleted ones included since they can be restored
+func countItemVariants(itemID uint) (int64, error) {
+	var total int64
+	for _, model := range []interface{}{&models.Pizza{}, &models.Beverage{}, &models.Topping{}} {
+		var count int64
+		if err := db.Unscoped().Model(model).Where("item_id = ?", itemID).Count(&count).Error; err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// Helper function to soft delete a catalog row: it is deactivated and gets a deleted_at
+// timestamp, so it disappears from the menu but stays referenced by past orders.
+func softDeleteRecord(model interface{}, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(model).Where("id = ?", id).Update("is_active", false)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Delete(model, id).Error
+	})
+}
+
+// Helper function to undo softDeleteRecord and load the restored row into model
+func restoreRecord(model interface{}, id uint) error {
+	return restoreWith(model, id, map[string]interface{}{})
+}
+
+// Helper function to restore a row priced in column, which must come back with
+// a price above 0: price replaces the stored one when given. The placeholders
+// repairVariantLinks inserts are priced 0 and would otherwise go on sale free.
+func restorePricedRecord(model interface{}, id uint, name, column string, price *models.Money) error {
+	var prices []models.Money
+	if err := db.Unscoped().Model(model).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Pluck(column, &prices).Error; err != nil {
+		return err
+	}
+	if len(prices) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	updates := map[string]interface{}{}
+	if price != nil {
+		if *price <= 0 {
+			return badRequest("%s must be greater than 0", column)
+		}
+		updates[column] = *price
+	} else if prices[0] <= 0 {
+		return conflict("%s %d has no price; send a %s above 0 to restore it", name, id, column)
+	}
+	return restoreWith(model, id, updates)
+}
+
+func restoreWith(model interface{}, id uint, updates map[string]interface{}) error {
+	updates["deleted_at"] = nil
+	updates["is_active"] = true
+	result := db.Unscoped().Model(model).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return db.First(model, id).Error
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to preload the active variants of items
+func preloadVariants(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Pizzas", func(db *gorm.DB) *gorm.DB {
+			return db.Where("is_active = ?", true).Order("price")
+		}).
+		Preload("Beverages", func(db *gorm.DB) *gorm.DB {
+			return db.Where("is_active = ?", true).Order("price")
+		}).
+		Preload("Toppings", func(db *gorm.DB) *gorm.DB {
+			return db.Where("is_active = ?", true).Order("name")
+		})
+}
+
+func newCatalogItemResponse(item models.Item) CatalogItemResponse {
+	resp := CatalogItemResponse{Item: item, Sizes: []string{}, BaseTypes: []string{}}
+	seenSize := map[string]bool{}
+	seenBase := map[string]bool{}
+	for _, pizza := range item.Pizzas {
+		if !seenSize[pizza.Size] {
+			seenSize[pizza.Size] = true
+			resp.Sizes = append(resp.Sizes, pizza.Size)
+		}
+		if !seenBase[pizza.BaseType] {
+			seenBase[pizza.BaseType] = true
+			resp.BaseTypes = append(resp.BaseTypes, pizza.BaseType)
+		}
+	}
+	for _, beverage := range item.Beverages {
+		if !seenSize[beverage.Size] {
+			seenSize[beverage.Size] = true
+			resp.Sizes = append(resp.Sizes, beverage.Size)
+		}
+	}
+	return resp
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// documentSeries describes a numbered document type and where its numbers are stored
+type documentSeries struct {
+	Prefix        string
+	FormatEnv     string // Environment variable overriding DefaultFormat
+	DefaultFormat string
+	Table         string // Table and column holding issued numbers, used to seed a new sequence
+	Column        string
+}
+
+var invoiceSeries = documentSeries{
+	Prefix:        "INV",
+	FormatEnv:     "INVOICE_NUMBER_FORMAT",
+	DefaultFormat: "{prefix}-{year}-{seq:6}",
+	Table:         "invoices",
+	Column:        "invoice_number",
+}
+
+var creditNoteSeries = documentSeries{
+	Prefix:        "CN",
+	FormatEnv:     "CREDIT_NOTE_NUMBER_FORMAT",
+	DefaultFormat: "{prefix}-{year}-{seq:6}",
+	Table:         "credit_notes",
+	Column:        "credit_note_number",
+}
+
+var seqPlaceholder = regexp.MustCompile(`\{seq(?::(\d+))?\}`)
+
+// format returns the number format for the series. Supported placeholders are
+// {prefix}, {store}, {year}, {yy} and {seq} or {seq:N} for N zero-padded digits.
+// Each year has its own sequence. With {year} or {yy} numbers restart every year;
+// without them a new year's sequence continues from the highest number issued.
+func (s documentSeries) format() string {
+	if format := os.Getenv(s.FormatEnv); strings.Contains(format, "{seq") {
+		return format
+	}
+	return s.DefaultFormat
+}
+
+// expand fills in every placeholder of text except {seq}
+func (s documentSeries) expand(text, store string, year int) string {
+	return strings.NewReplacer(
+		"{prefix}", s.Prefix,
+		"{store}", store,
+		"{year}", strconv.Itoa(year),
+		"{yy}", fmt.Sprintf("%02d", year%100),
+	).Replace(text)
+}
+
+func (s documentSeries) render(store string, year int, seq int64) string {
+	return seqPlaceholder.ReplaceAllStringFunc(s.expand(s.format(), store, year), func(match string) string {
+		width := 0
+		if digits := seqPlaceholder.FindStringSubmatch(match)[1]; digits != "" {
+			width, _ = strconv.Atoi(digits)
+		}
+		return fmt.Sprintf("%0*d", width, seq)
+	})
+}
+
+// storeCode identifies this shop in multi-store numbering, from STORE_CODE
+func storeCode() string {
+	return strings.TrimSpace(os.Getenv("STORE_CODE"))
+}
+
+// nextDocumentNumber reserves the next number of a series. It must run inside
+// tx: the sequence row stays locked (SELECT ... FOR UPDATE) until tx ends, so
+// concurrent cashiers queue instead of colliding, and a rolled back tx hands
+// its number back so the series has no gaps.
+func nextDocumentNumber(tx *gorm.DB, series documentSeries, date time.Time) (string, error) {
+	store := storeCode()
+	year := date.Year()
+
+	var sequence models.DocumentSequence
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("prefix = ? AND year = ? AND store_code = ?", series.Prefix, year, store).
+		First(&sequence).Error
+	if err == gorm.ErrRecordNotFound {
+		seed, seedErr := seedSequence(tx, series, store, year)
+		if seedErr != nil {
+			return "", seedErr
+		}
+
+		// Another transaction may create the row first; then we simply lock theirs
+		sequence = models.DocumentSequence{Prefix: series.Prefix, Year: year, StoreCode: store, LastValue: seed}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sequence).Error; err != nil {
+			return "", err
+		}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("prefix = ? AND year = ? AND store_code = ?", series.Prefix, year, store).
+			First(&sequence).Error
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sequence.LastValue++
+	if err := tx.Model(&sequence).Update("last_value", sequence.LastValue).Error; err != nil {
+		return "", err
+	}
+
+	return series.render(store, year, sequence.LastValue), nil
+}
+
+// seedSequence continues from numbers issued before the sequence row existed
+// (including soft-deleted documents), so a new sequence never reuses them
+func seedSequence(tx *gorm.DB, series documentSeries, store string, year int) (int64, error) {
+	fixed := series.fixedPart(store, year)
+
+	var numbers []string
+	if err := tx.Table(series.Table).
+		Where(series.Column+" LIKE ?", escapeLike(fixed)+"%").
+		Pluck(series.Column, &numbers).Error; err != nil {
+		return 0, err
+	}
+	return lastSequence(fixed, numbers), nil
+}
+
+// fixedPart is everything before {seq}, which is fixed for a prefix, store and
+// year; numbers of another year do not share it, so each year starts again
+func (s documentSeries) fixedPart(store string, year int) string {
+	format := s.format()
+	loc := seqPlaceholder.FindStringIndex(format)
+	return s.expand(format[:loc[0]], store, year)
+}
+
+// lastSequence returns the highest sequence among numbers starting with fixed
+func lastSequence(fixed string, numbers []string) int64 {
+	var last int64
+	for _, number := range numbers {
+		if !strings.HasPrefix(number, fixed) {
+			continue
+		}
+		digits := strings.TrimLeft(number[len(fixed):], " ")
+		end := 0
+		for end < len(digits) && digits[end] >= '0' && digits[end] <= '9' {
+			end++
+		}
+		if value, err := strconv.ParseInt(digits[:end], 10, 64); err == nil && value > last {
+			last = value
+		}
+	}
+	return last
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import "testing"
+
+func TestDocumentSeriesRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		store  string
+		year   int
+		seq    int64
+		want   string
+	}{
+		{"default format", "", "", 2025, 42, "INV-2025-000042"},
+		{"two digit year", "{prefix}{yy}/{seq:4}", "", 2025, 7, "INV25/0007"},
+		{"store code", "{store}-{prefix}-{year}-{seq:5}", "COL01", 2026, 123, "COL01-INV-2026-00123"},
+		{"no padding", "{prefix}-{seq}", "", 2025, 9, "INV-9"},
+		{"sequence wider than padding", "{prefix}-{seq:3}", "", 2025, 12345, "INV-12345"},
+		{"format without seq falls back", "{prefix}-{year}", "", 2025, 1, "INV-2025-000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INVOICE_NUMBER_FORMAT", tt.format)
+			if got := invoiceSeries.render(tt.store, tt.year, tt.seq); got != tt.want {
+				t.Errorf("render(%q, %d, %d) = %q, want %q", tt.store, tt.year, tt.seq, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentSeriesYearlyReset(t *testing.T) {
+	t.Setenv("CREDIT_NOTE_NUMBER_FORMAT", "")
+	issued := []string{"CN-2025-000041", "CN-2025-000042", "CN-2026-000003"}
+
+	tests := []struct {
+		year int
+		want int64
+	}{
+		{2025, 42},
+		{2026, 3},
+		{2027, 0}, // A new year starts again at 1
+	}
+	for _, tt := range tests {
+		fixed := creditNoteSeries.fixedPart("", tt.year)
+		if got := lastSequence(fixed, issued); got != tt.want {
+			t.Errorf("last sequence of %d = %d, want %d", tt.year, got, tt.want)
+		}
+	}
+}
+
+func TestLastSequence(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixed   string
+		numbers []string
+		want    int64
+	}{
+		{"none issued", "INV-2025-", nil, 0},
+		{"highest wins", "INV-2025-", []string{"INV-2025-000009", "INV-2025-000120", "INV-2025-000011"}, 120},
+		{"suffix after the digits", "S1/INV/", []string{"S1/INV/17-A", "S1/INV/4"}, 17},
+		{"other store ignored", "S1-", []string{"S2-000500", "S1-000002"}, 2},
+		{"no digits", "INV-", []string{"INV-draft"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastSequence(tt.fixed, tt.numbers); got != tt.want {
+				t.Errorf("lastSequence(%q) = %d, want %d", tt.fixed, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"main/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// createOrder prices and stores a new pending order with its lines and first
+// history entry. Client prices are only checked, never billed, and tax comes
+// from the configured tax rates. Delivery orders get their zone's fee as an
+// extra line.
+func createOrder(tx *gorm.DB, req CreateOrderRequest) (models.Order, error) {
+	// The customer row stays share-locked so it cannot be deleted mid-order
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&customer, req.CustomerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Order{}, badRequest("Customer with ID %d not found", req.CustomerID)
+		}
+		return models.Order{}, err
+	}
+
+	order := models.Order{
+		CustomerID:  req.CustomerID,
+		OrderDate:   time.Now(),
+		OrderStatus: "pending",
+	}
+	if err := applyFulfilment(tx, &order, req); err != nil {
+		return order, err
+	}
+
+	var itemsTotal models.Money
+	orderItems := make([]models.OrderItem, 0, len(req.Items)+1)
+	for _, item := range req.Items {
+		orderItem, err := buildOrderItem(tx, item)
+		if err != nil {
+			return order, err
+		}
+		if orderItem.PriceMismatch {
+			log.Printf("Client price %s for item %d differs from menu price %s", *orderItem.ClientUnitPrice, orderItem.ItemID, orderItem.UnitPrice)
+		}
+		itemsTotal += orderItem.TotalPrice
+		orderItems = append(orderItems, orderItem)
+	}
+
+	if order.FulfilmentType == "delivery" {
+		if err := applyDeliveryZone(tx, &order, itemsTotal); err != nil {
+			return order, err
+		}
+	}
+	if order.DeliveryFee > 0 {
+		feeLine, err := deliveryFeeLine(tx, order.DeliveryFee)
+		if err != nil {
+			return order, err
+		}
+		orderItems = append(orderItems, feeLine)
+	}
+
+	totals, err := calculateOrderTax(tx, orderItems)
+	if err != nil {
+		return order, err
+	}
+	order.Subtotal = totals.Subtotal
+	order.Tax = totals.Tax
+	order.TotalAmount = totals.Total
+
+	if err := tx.Create(&order).Error; err != nil {
+		return order, err
+	}
+	log.Printf("Order created with ID: %d", order.ID)
+
+	if err := recordOrderStatus(tx, order.ID, "", order.OrderStatus, req.CreatedBy, "", order.OrderDate); err != nil {
+		return order, err
+	}
+
+	for i := range orderItems {
+		orderItems[i].OrderID = order.ID
+		if err := tx.Create(&orderItems[i]).Error; err != nil {
+			return order, err
+		}
+	}
+	order.OrderItems = orderItems
+
+	return order, nil
+}
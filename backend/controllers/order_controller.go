@@ -17,14 +17,89 @@ import (
 
 type CreateOrderRequest struct {
 	CustomerID uint                     `json:"customer_id" binding:"required"`
-	Tax        float64                  `json:"tax"`
 	Items      []CreateOrderItemRequest `json:"items" binding:"required"`
+	CreatedBy  string                   `json:"created_by"` // Staff member taking the order
+	Tax        *models.Money            `json:"tax"`        // No longer accepted: tax comes from the tax rates
+
+	FulfilmentType    string     `json:"fulfilment_type"`     // dine_in, takeaway (default) or delivery
+	TableNumber       string     `json:"table_number"`        // Dine-in
+	PickupTime        *time.Time `json:"pickup_time"`         // Takeaway, optional
+	Courier           string     `json:"courier"`             // Delivery, defaults to in_house
+	DeliveryAddressID *uint      `json:"delivery_address_id"` // Delivery, defaults to the customer's default address
+	DeliveryNotes     string     `json:"delivery_notes"`      // Replaces the address's own delivery notes
 }
 
+// maxLineQuantity is the most units of one line, or portions of one topping,
+// an order may ask for
+const maxLineQuantity = 1000
+
 type CreateOrderItemRequest struct {
-	ItemID   uint    `json:"item_id" binding:"required"`
-	Quantity int     `json:"quantity" binding:"required"`
-	Price    float64 `json:"price" binding:"required"` // Unit price
+	ItemID     uint          `json:"item_id" binding:"required"`
+	PizzaID    *uint         `json:"pizza_id"`    // Optional variant the line is priced from
+	BeverageID *uint         `json:"beverage_id"` // Optional variant the line is priced from
+	ToppingID  *uint         `json:"topping_id"`  // Optional variant the line is priced from
+	Quantity   int           `json:"quantity" binding:"required"`
+	Price      *models.Money `json:"price"` // Unit price quoted by the client, checked against the menu
+
+	Toppings []CreateOrderItemToppingRequest `json:"toppings"` // Pizza lines only
+}
+
+// validate checks an order line before it is priced; it returns "" when the line is usable
+func (line CreateOrderItemRequest) validate() string {
+	if line.ItemID == 0 {
+		return "Item ID is required"
+	}
+	if line.Quantity <= 0 {
+		return "Quantity must be greater than 0"
+	}
+	if line.Quantity > maxLineQuantity {
+		return fmt.Sprintf("Quantity cannot be more than %d", maxLineQuantity)
+	}
+	if line.Price != nil && *line.Price < 0 {
+		return "Price cannot be negative"
+	}
+	return ""
+}
+
+type CreateOrderItemToppingRequest struct {
+	ToppingID uint   `json:"topping_id" binding:"required"`
+	Action    string `json:"action"`   // "add" (default) for an extra topping, "remove" to leave one off
+	Quantity  int    `json:"quantity"` // Portions per pizza, defaults to 1
+}
+
+type UpdateOrderStatusRequest struct {
+	Status    string `json:"status" binding:"required"`
+	ChangedBy string `json:"changed_by"` // Staff member making the change, kept in the order's history
+	Note      string `json:"note"`
+}
+
+type CancelOrderRequest struct {
+	Reason       string `json:"reason" binding:"required"`
+	RefundMethod string `json:"refund_method"` // Required when the invoice is already paid
+	ChangedBy    string `json:"changed_by"`
+
+	CashSessionID *uint `json:"cash_session_id"` // Drawer a cash refund is paid from
+}
+
+// CancelOrderResponse is the cancelled order and, if its invoice was paid, the credit note refunding it
+type CancelOrderResponse struct {
+	Order      models.Order       `json:"order"`
+	CreditNote *models.CreditNote `json:"credit_note"`
+}
+
+// OrderTimeline is an order's progress through the status state machine
+type OrderTimeline struct {
+	OrderID          uint                        `json:"order_id"`
+	Status           string                      `json:"status"`
+	PlacedAt         time.Time                   `json:"placed_at"`
+	ConfirmedAt      *time.Time                  `json:"confirmed_at"`
+	PreparingAt      *time.Time                  `json:"preparing_at"`
+	ReadyAt          *time.Time                  `json:"ready_at"`
+	OutForDeliveryAt *time.Time                  `json:"out_for_delivery_at"`
+	DeliveredAt      *time.Time                  `json:"delivered_at"`
+	CancelledAt      *time.Time                  `json:"cancelled_at"`
+	NextStatuses     []string                    `json:"next_statuses"` // Statuses the order may move to now
+	History          []models.OrderStatusHistory `json:"history"`
 }
 
 func GetOrders(w http.ResponseWriter, r *http.Request) {
@@ -48,11 +123,33 @@ func GetOrders(w http.ResponseWriter, r *http.Request) {
 
 	offset := (page - 1) * limit
 
+	// Parse filters
+	query := db.Model(&models.Order{})
+	if customerParam := r.URL.Query().Get("customer_id"); customerParam != "" {
+		customerID, err := strconv.ParseUint(customerParam, 10, 32)
+		if err != nil {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Invalid customer ID",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusBadRequest, response)
+			return
+		}
+		query = query.Where("customer_id = ?", uint(customerID))
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("order_status = ?", status)
+	}
+	if fulfilmentType := r.URL.Query().Get("fulfilment_type"); fulfilmentType != "" {
+		query = query.Where("fulfilment_type = ?", fulfilmentType)
+	}
+
 	var orders []models.Order
 	var totalCount int64
 
 	// Get total count for pagination
-	if err := db.Model(&models.Order{}).Count(&totalCount).Error; err != nil {
+	if err := query.Session(&gorm.Session{}).Count(&totalCount).Error; err != nil {
 		log.Printf("Error counting orders: %v", err)
 		response := utils.APIResponse{
 			Success: false,
@@ -64,7 +161,7 @@ func GetOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch orders with pagination, including order items and their associated items
-	if err := db.Preload("OrderItems.Item").
+	if err := preloadOrderItems(query, "").
 		Offset(offset).
 		Limit(limit).
 		Order("created_at DESC").
@@ -117,7 +214,7 @@ func GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var order models.Order
-	if err := db.Preload("OrderItems").Preload("OrderItems.Item").
+	if err := preloadOrderItems(db, "").
 		Where("id = ?", uint(orderID)).
 		First(&order).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -162,6 +259,16 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Tax != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Tax is calculated from the configured tax rates; remove tax from the request",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
 	// Validate required fields
 	if req.CustomerID == 0 {
 		response := utils.APIResponse{
@@ -183,169 +290,194 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate items and calculate total
-	var totalAmount float64
+	// Validate items
 	for i, item := range req.Items {
-		if item.ItemID == 0 {
+		if msg := item.validate(); msg != "" {
 			response := utils.APIResponse{
 				Success: false,
-				Message: fmt.Sprintf("Item ID is required for item %d", i+1),
+				Message: fmt.Sprintf("%s for item %d", msg, i+1),
 				Data:    nil,
 			}
 			utils.SendJSONResponse(w, http.StatusBadRequest, response)
 			return
 		}
+	}
 
-		if item.Quantity <= 0 {
-			response := utils.APIResponse{
-				Success: false,
-				Message: fmt.Sprintf("Quantity must be greater than 0 for item %d", i+1),
-				Data:    nil,
-			}
-			utils.SendJSONResponse(w, http.StatusBadRequest, response)
-			return
-		}
+	var order models.Order
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = createOrder(tx, req)
+		return err
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to create order")
+		return
+	}
 
-		if item.Price < 0 {
-			response := utils.APIResponse{
-				Success: false,
-				Message: fmt.Sprintf("Price cannot be negative for item %d", i+1),
-				Data:    nil,
-			}
-			utils.SendJSONResponse(w, http.StatusBadRequest, response)
-			return
+	// Fetch the created order with items for response
+	var createdOrder models.Order
+	if err := preloadOrderItems(db, "").First(&createdOrder, order.ID).Error; err != nil {
+		log.Printf("Error fetching created order: %v", err)
+		// Order was created successfully, but we couldn't fetch it for response
+		response := utils.APIResponse{
+			Success: true,
+			Message: "Order created successfully",
+			Data:    map[string]interface{}{"order_id": order.ID},
 		}
+		utils.SendJSONResponse(w, http.StatusCreated, response)
+		return
+	}
 
-		totalAmount += item.Price * float64(item.Quantity)
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Order created successfully",
+		Data:    createdOrder,
 	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
 
-	// Add tax to total
-	totalAmount += req.Tax
+func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("PUT /api/orders/%s/status called", id)
 
-	// Start transaction
-	tx := db.Begin()
-	if tx.Error != nil {
-		log.Printf("Error starting transaction: %v", tx.Error)
+	orderID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to create order",
+			Message: "Invalid order ID",
 			Data:    nil,
 		}
-		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	// Create order using your model structure
-	order := models.Order{
-		CustomerID:  req.CustomerID,
-		OrderDate:   time.Now(),
-		TotalAmount: totalAmount,
-		Tax:         req.Tax,
-		OrderStatus: "pending",
-	}
-
-	if err := tx.Create(&order).Error; err != nil {
-		tx.Rollback()
-		log.Printf("Error creating order: %v", err)
+	var req UpdateOrderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to create order",
+			Message: "Invalid request body",
 			Data:    nil,
 		}
-		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	log.Printf("Order created with ID: %d", order.ID)
+	// Move the order along the state machine; the row lock keeps concurrent updates in order
+	err = db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, uint(orderID))
+		if err != nil {
+			return err
+		}
+		if req.Status == "cancelled" {
+			// Cancelling also settles the invoice; refunds need POST /orders/{id}/cancel
+			_, err := cancelOrder(tx, &order, CancelOrderRequest{Reason: req.Note, ChangedBy: req.ChangedBy})
+			return err
+		}
+		return transitionOrderStatus(tx, &order, req.Status, req.ChangedBy, req.Note)
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to update order status")
+		return
+	}
 
-	// Create order items
-	for i, item := range req.Items {
-		// Verify item exists and is active (assuming you have IsActive field)
-		var dbItem models.Item
-		if err := tx.Where("id = ?", item.ItemID).First(&dbItem).Error; err != nil {
-			tx.Rollback()
-			if err == gorm.ErrRecordNotFound {
-				response := utils.APIResponse{
-					Success: false,
-					Message: fmt.Sprintf("Item with ID %d not found", item.ItemID),
-					Data:    nil,
-				}
-				utils.SendJSONResponse(w, http.StatusBadRequest, response)
-				return
-			}
-			log.Printf("Error verifying item %d: %v", item.ItemID, err)
-			response := utils.APIResponse{
-				Success: false,
-				Message: "Failed to create order",
-				Data:    nil,
-			}
-			utils.SendJSONResponse(w, http.StatusInternalServerError, response)
-			return
+	// Fetch updated order
+	var updatedOrder models.Order
+	if err := preloadOrderItems(db, "").First(&updatedOrder, uint(orderID)).Error; err != nil {
+		log.Printf("Error fetching updated order: %v", err)
+		response := utils.APIResponse{
+			Success: true,
+			Message: "Order status updated successfully",
+			Data:    map[string]interface{}{"order_id": orderID, "status": req.Status},
 		}
+		utils.SendJSONResponse(w, http.StatusOK, response)
+		return
+	}
 
-		// Calculate total price for this order item
-		totalPrice := item.Price * float64(item.Quantity)
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Order status updated successfully",
+		Data:    updatedOrder,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
 
-		orderItem := models.OrderItem{
-			OrderID:    order.ID,
-			ItemID:     item.ItemID,
-			Quantity:   item.Quantity,
-			TotalPrice: totalPrice, // Using TotalPrice field from your model
+func GetOrderTimeline(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/orders/%s/timeline called", id)
+
+	orderID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid order ID",
+			Data:    nil,
 		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
 
-		if err := tx.Create(&orderItem).Error; err != nil {
-			tx.Rollback()
-			log.Printf("Error creating order item %d: %v", i+1, err)
+	var order models.Order
+	if err := db.First(&order, uint(orderID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
 			response := utils.APIResponse{
 				Success: false,
-				Message: "Failed to create order",
+				Message: "Order not found",
 				Data:    nil,
 			}
-			utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
 			return
 		}
 
-		log.Printf("Order item %d created successfully", i+1)
-	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		log.Printf("Error committing transaction: %v", err)
+		log.Printf("Error fetching order: %v", err)
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Failed to create order",
+			Message: "Failed to fetch order",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
 		return
 	}
 
-	// Fetch the created order with items for response
-	var createdOrder models.Order
-	if err := db.Preload("OrderItems").Preload("OrderItems.Item").First(&createdOrder, order.ID).Error; err != nil {
-		log.Printf("Error fetching created order: %v", err)
-		// Order was created successfully, but we couldn't fetch it for response
+	var history []models.OrderStatusHistory
+	if err := db.Where("order_id = ?", order.ID).Order("changed_at, id").Find(&history).Error; err != nil {
+		log.Printf("Error fetching order status history: %v", err)
 		response := utils.APIResponse{
-			Success: true,
-			Message: "Order created successfully",
-			Data:    map[string]interface{}{"order_id": order.ID},
+			Success: false,
+			Message: "Failed to fetch order timeline",
+			Data:    nil,
 		}
-		utils.SendJSONResponse(w, http.StatusCreated, response)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
 		return
 	}
 
+	timeline := OrderTimeline{
+		OrderID:          order.ID,
+		Status:           order.OrderStatus,
+		PlacedAt:         order.OrderDate,
+		ConfirmedAt:      order.ConfirmedAt,
+		PreparingAt:      order.PreparingAt,
+		ReadyAt:          order.ReadyAt,
+		OutForDeliveryAt: order.OutForDeliveryAt,
+		DeliveredAt:      order.DeliveredAt,
+		CancelledAt:      order.CancelledAt,
+		NextStatuses:     nextOrderStatuses(order.FulfilmentType, order.OrderStatus),
+		History:          history,
+	}
+
 	response := utils.APIResponse{
 		Success: true,
-		Message: "Order created successfully",
-		Data:    createdOrder,
+		Message: "Order timeline retrieved successfully",
+		Data:    timeline,
 	}
-	utils.SendJSONResponse(w, http.StatusCreated, response)
+	utils.SendJSONResponse(w, http.StatusOK, response)
 }
 
-func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+func CancelOrder(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	log.Printf("PUT /api/orders/%s/status called", id)
+	log.Printf("POST /api/orders/%s/cancel called", id)
 
 	orderID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
@@ -358,10 +490,7 @@ func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Status string `json:"status" binding:"required"`
-	}
-
+	var req CancelOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		response := utils.APIResponse{
 			Success: false,
@@ -372,65 +501,38 @@ func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate status values (adjust as needed for your business logic)
-	validStatuses := []string{"pending", "confirmed", "preparing", "ready", "delivered", "cancelled"}
-	isValidStatus := false
-	for _, status := range validStatuses {
-		if req.Status == status {
-			isValidStatus = true
-			break
-		}
-	}
-
-	if !isValidStatus {
+	if req.Reason == "" {
 		response := utils.APIResponse{
 			Success: false,
-			Message: "Invalid order status",
+			Message: "Reason is required",
 			Data:    nil,
 		}
 		utils.SendJSONResponse(w, http.StatusBadRequest, response)
 		return
 	}
 
-	// Update order status
-	if err := db.Model(&models.Order{}).Where("id = ?", uint(orderID)).Update("order_status", req.Status).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			response := utils.APIResponse{
-				Success: false,
-				Message: "Order not found",
-				Data:    nil,
-			}
-			utils.SendJSONResponse(w, http.StatusNotFound, response)
-			return
-		}
-
-		log.Printf("Error updating order status: %v", err)
-		response := utils.APIResponse{
-			Success: false,
-			Message: "Failed to update order status",
-			Data:    nil,
+	var result CancelOrderResponse
+	err = db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, uint(orderID))
+		if err != nil {
+			return err
 		}
-		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		result.CreditNote, err = cancelOrder(tx, &order, req)
+		return err
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to cancel order")
 		return
 	}
 
-	// Fetch updated order
-	var updatedOrder models.Order
-	if err := db.Preload("OrderItems").Preload("OrderItems.Item").First(&updatedOrder, uint(orderID)).Error; err != nil {
-		log.Printf("Error fetching updated order: %v", err)
-		response := utils.APIResponse{
-			Success: true,
-			Message: "Order status updated successfully",
-			Data:    map[string]interface{}{"order_id": orderID, "status": req.Status},
-		}
-		utils.SendJSONResponse(w, http.StatusOK, response)
-		return
+	if err := preloadOrderItems(db, "").First(&result.Order, uint(orderID)).Error; err != nil {
+		log.Printf("Error fetching cancelled order: %v", err)
 	}
 
 	response := utils.APIResponse{
 		Success: true,
-		Message: "Order status updated successfully",
-		Data:    updatedOrder,
+		Message: "Order cancelled successfully",
+		Data:    result,
 	}
 	utils.SendJSONResponse(w, http.StatusOK, response)
 }
@@ -0,0 +1,318 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"main/models"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type UpdateOrderItemRequest struct {
+	Quantity int `json:"quantity" binding:"required"`
+}
+
+// amendableStatuses are the order statuses in which lines may still change;
+// once the kitchen starts preparing, the order is fixed
+var amendableStatuses = map[string]bool{
+	"pending":   true,
+	"confirmed": true,
+}
+
+func AddOrderItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/orders/%s/items called", id)
+
+	orderID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid order ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req CreateOrderItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if msg := req.validate(); msg != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	order, err := amendOrder(uint(orderID), func(tx *gorm.DB, order *models.Order) error {
+		orderItem, err := buildOrderItem(tx, req)
+		if err != nil {
+			return err
+		}
+		orderItem.OrderID = order.ID
+		return tx.Create(&orderItem).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to add order item")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Order item added successfully",
+		Data:    order,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func UpdateOrderItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	itemID := vars["itemId"]
+	log.Printf("PUT /api/orders/%s/items/%s called", id, itemID)
+
+	orderID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid order ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	orderItemID, err := strconv.ParseUint(itemID, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid order item ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req UpdateOrderItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if req.Quantity <= 0 {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Quantity must be greater than 0; delete the line to remove it",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+	if req.Quantity > maxLineQuantity {
+		sendRequestError(w, badRequest("Quantity cannot be more than %d", maxLineQuantity), "")
+		return
+	}
+
+	order, err := amendOrder(uint(orderID), func(tx *gorm.DB, order *models.Order) error {
+		orderItem, err := findOrderItem(tx, order.ID, uint(orderItemID))
+		if err != nil {
+			return err
+		}
+		// Prices stay as snapshotted when the line was ordered
+		totalPrice, err := (orderItem.UnitPrice + orderItem.ToppingsPrice).Times(req.Quantity)
+		if err != nil {
+			return badRequest("Total of order item %d is too large: %v", orderItem.ID, err)
+		}
+		return tx.Model(&orderItem).Updates(map[string]interface{}{
+			"quantity":    req.Quantity,
+			"total_price": totalPrice,
+		}).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to update order item")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Order item updated successfully",
+		Data:    order,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func DeleteOrderItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	itemID := vars["itemId"]
+	log.Printf("DELETE /api/orders/%s/items/%s called", id, itemID)
+
+	orderID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid order ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	orderItemID, err := strconv.ParseUint(itemID, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid order item ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	order, err := amendOrder(uint(orderID), func(tx *gorm.DB, order *models.Order) error {
+		orderItem, err := findOrderItem(tx, order.ID, uint(orderItemID))
+		if err != nil {
+			return err
+		}
+
+		var remaining int64
+		if err := tx.Model(&models.OrderItem{}).Where("order_id = ? AND id <> ? AND delivery_fee = ?", order.ID, orderItem.ID, false).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining == 0 {
+			return conflict("Cannot remove the last item of an order; cancel the order instead")
+		}
+		return tx.Delete(&orderItem).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to delete order item")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Order item deleted successfully",
+		Data:    order,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+// amendOrder applies a change to an order's lines in one transaction: it locks
+// the order, checks the order can still change, runs change, recalculates the
+// order totals and tax, and refreshes the invoice if one was already issued.
+// The updated order is returned with its lines.
+func amendOrder(orderID uint, change func(tx *gorm.DB, order *models.Order) error) (models.Order, error) {
+	var order models.Order
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = lockOrder(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if !amendableStatuses[order.OrderStatus] {
+			return conflict("Order is %s and can no longer be changed", order.OrderStatus)
+		}
+
+		// Locked so no payment lands while the totals are rewritten. An
+		// overdue invoice may hold part payments, so those are refused too.
+		invoice, err := lockInvoice(tx, "order_id = ?", order.ID)
+		if err != nil {
+			return err
+		}
+		if invoice != nil && invoice.PaymentStatus != "pending" && invoice.PaymentStatus != "overdue" {
+			return conflict("Invoice %s is %s; the order can no longer be changed", invoice.InvoiceNumber, invoice.PaymentStatus)
+		}
+		if invoice != nil && invoice.AmountPaid != 0 {
+			return conflict("Invoice %s already has payments of %s; the order can no longer be changed", invoice.InvoiceNumber, invoice.AmountPaid)
+		}
+
+		if err := change(tx, &order); err != nil {
+			return err
+		}
+
+		if err := tx.Preload("OrderItems").First(&order, order.ID).Error; err != nil {
+			return err
+		}
+		if err := checkDeliveryMinimum(tx, order); err != nil {
+			return err
+		}
+		totals, err := applyOrderTax(tx, &order)
+		if err != nil {
+			return err
+		}
+		if invoice != nil {
+			return refreshInvoiceTotals(tx, invoice, totals)
+		}
+		return nil
+	})
+	if err != nil {
+		return order, err
+	}
+
+	var updated models.Order
+	err = preloadOrderItems(db, "").First(&updated, orderID).Error
+	return updated, err
+}
+
+// findOrderItem loads one line of an order that may be amended
+func findOrderItem(tx *gorm.DB, orderID, orderItemID uint) (models.OrderItem, error) {
+	var orderItem models.OrderItem
+	err := tx.Where("id = ? AND order_id = ?", orderItemID, orderID).First(&orderItem).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return orderItem, notFound("Order item %d not found on order %d", orderItemID, orderID)
+	}
+	if err == nil && orderItem.DeliveryFee {
+		return orderItem, conflict("Order item %d is the delivery fee and cannot be changed", orderItemID)
+	}
+	return orderItem, err
+}
+
+// refreshInvoiceTotals rewrites an unpaid invoice's amounts and tax breakdown
+// after its order changed; the invoice keeps its number and date
+func refreshInvoiceTotals(tx *gorm.DB, invoice *models.Invoice, totals taxResult) error {
+	invoice.SubtotalAmount = totals.Subtotal
+	invoice.TaxAmount = totals.Tax
+	invoice.TotalAmount = totals.Total
+	if err := tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Updates(map[string]interface{}{
+		"subtotal_amount": invoice.SubtotalAmount,
+		"tax_amount":      invoice.TaxAmount,
+		"total_amount":    invoice.TotalAmount,
+	}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceTax{}).Error; err != nil {
+		return err
+	}
+	invoice.Taxes = invoiceTaxes(totals.Breakdown)
+	for i := range invoice.Taxes {
+		invoice.Taxes[i].InvoiceID = invoice.ID
+	}
+	if len(invoice.Taxes) == 0 {
+		return nil
+	}
+	return tx.Create(&invoice.Taxes).Error
+}
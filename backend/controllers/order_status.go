@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"main/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// finalOrderStatuses are the statuses an order cannot leave
+var finalOrderStatuses = []string{"delivered", "cancelled"}
+
+// orderTransitions lists the statuses an order may move to from each status.
+// Delivered and cancelled orders are final. Dine-in orders are delivered when
+// served and takeaway orders when collected.
+var orderTransitions = map[string][]string{
+	"pending":          {"confirmed", "cancelled"},
+	"confirmed":        {"preparing", "cancelled"},
+	"preparing":        {"ready", "cancelled"},
+	"ready":            {"delivered", "cancelled"},
+	"out_for_delivery": {"delivered", "cancelled"},
+	"delivered":        {},
+	"cancelled":        {},
+}
+
+// fulfilmentTransitions replaces steps of orderTransitions for a fulfilment
+// type: delivery orders leave the shop before they are delivered
+var fulfilmentTransitions = map[string]map[string][]string{
+	"delivery": {
+		"ready": {"out_for_delivery", "cancelled"},
+	},
+}
+
+// orderStageColumns maps a status to the order column stamped when it is first reached
+var orderStageColumns = map[string]string{
+	"confirmed":        "confirmed_at",
+	"preparing":        "preparing_at",
+	"ready":            "ready_at",
+	"out_for_delivery": "out_for_delivery_at",
+	"delivered":        "delivered_at",
+	"cancelled":        "cancelled_at",
+}
+
+// nextOrderStatuses lists the statuses an order of the fulfilment type may move to from status
+func nextOrderStatuses(fulfilmentType, status string) []string {
+	if next, ok := fulfilmentTransitions[fulfilmentType][status]; ok {
+		return next
+	}
+	return orderTransitions[status]
+}
+
+func canTransitionOrder(fulfilmentType, from, to string) bool {
+	for _, next := range nextOrderStatuses(fulfilmentType, from) {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// lockOrder loads an order and locks its row until the transaction ends, so
+// concurrent status changes and amendments are applied one after the other
+func lockOrder(tx *gorm.DB, orderID uint) (models.Order, error) {
+	var order models.Order
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return order, notFound("Order not found")
+	}
+	return order, err
+}
+
+// transitionOrderStatus moves an order to a new status if the state machine
+// allows it, stamps the stage timestamp, moves the delivery assignment along
+// and appends to the order's history. It is the only place order_status is
+// changed after an order is created.
+func transitionOrderStatus(tx *gorm.DB, order *models.Order, to, changedBy, note string) error {
+	from := order.OrderStatus
+	if _, ok := orderTransitions[to]; !ok {
+		return badRequest("Invalid order status %q", to)
+	}
+	if from == to {
+		return conflict("Order is already %s", to)
+	}
+	if !canTransitionOrder(order.FulfilmentType, from, to) {
+		allowed := strings.Join(nextOrderStatuses(order.FulfilmentType, from), ", ")
+		if allowed == "" {
+			return conflict("Order is %s and can no longer change status", from)
+		}
+		return conflict("Cannot change order status from %s to %s. Allowed: %s", from, to, allowed)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"order_status": to}
+	if column, ok := orderStageColumns[to]; ok {
+		updates[column] = now
+	}
+	if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	if err := updateDeliveryAssignment(tx, order, to, now); err != nil {
+		return err
+	}
+
+	order.OrderStatus = to
+	return recordOrderStatus(tx, order.ID, from, to, changedBy, note, now)
+}
+
+// recordOrderStatus appends one entry to an order's status history
+func recordOrderStatus(tx *gorm.DB, orderID uint, from, to, changedBy, note string, at time.Time) error {
+	return tx.Create(&models.OrderStatusHistory{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		ChangedBy:  strings.TrimSpace(changedBy),
+		Note:       strings.TrimSpace(note),
+		ChangedAt:  at,
+	}).Error
+}
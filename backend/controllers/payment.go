@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"main/models"
+
+	"gorm.io/gorm"
+)
+
+// validPaymentMethods are the ways an invoice can be paid
+var validPaymentMethods = map[string]bool{
+	"cash":          true,
+	"card":          true,
+	"bank_transfer": true,
+	"voucher":       true,
+}
+
+// validPaymentStatuses are all the statuses an invoice can have
+var validPaymentStatuses = map[string]bool{
+	"pending":        true,
+	"partially_paid": true,
+	"paid":           true,
+	"overpaid":       true,
+	"overdue":        true,
+	"cancelled":      true,
+	"refunded":       true,
+}
+
+// paidStatuses are the invoice statuses in which the invoice is settled
+var paidStatuses = map[string]bool{
+	"paid":     true,
+	"overpaid": true,
+}
+
+// cashRoundingIncrement returns CASH_ROUNDING_INCREMENT, the step cash amounts
+// are rounded to (e.g. 5 or 10 when the smallest coin is 5 LKR); 0 disables it
+func cashRoundingIncrement() models.Money {
+	increment, err := models.ParseMoney(os.Getenv("CASH_ROUNDING_INCREMENT"))
+	if err != nil || increment <= 0 {
+		return 0
+	}
+	return increment
+}
+
+// cashDue is what a customer settling a balance in cash pays: the balance
+// rounded to the nearest cash rounding increment
+func cashDue(balance models.Money) models.Money {
+	if balance <= 0 {
+		return balance
+	}
+	return balance.RoundTo(cashRoundingIncrement())
+}
+
+// recordPayment adds a payment to a locked invoice and updates the invoice's
+// payment status. For cash, an amount left at 0 takes as much of the tendered
+// cash as the balance needs, and the rest is given back as change. Cash that
+// settles the rounded balance writes the rounding difference off as a
+// separate cash_rounding payment, so the drawer only holds real cash. A cash
+// balance that rounds to nothing is written off whole as cash_rounding, with
+// no cash taken.
+func recordPayment(tx *gorm.DB, invoice *models.Invoice, req RecordPaymentRequest) (models.Payment, error) {
+	payment := models.Payment{
+		InvoiceID:  invoice.ID,
+		Method:     strings.ToLower(strings.TrimSpace(req.Method)),
+		Amount:     req.Amount,
+		Tendered:   req.Tendered,
+		Reference:  strings.TrimSpace(req.Reference),
+		ReceivedBy: strings.TrimSpace(req.ReceivedBy),
+		PaidAt:     time.Now(),
+	}
+
+	if invoice.PaymentStatus == "cancelled" || invoice.PaymentStatus == "refunded" {
+		return payment, conflict("Invoice %s is %s and cannot take payments", invoice.InvoiceNumber, invoice.PaymentStatus)
+	}
+	if !validPaymentMethods[payment.Method] {
+		return payment, badRequest("Invalid payment method %q. Must be one of: cash, card, bank_transfer, voucher", req.Method)
+	}
+	if payment.Amount < 0 || payment.Tendered < 0 {
+		return payment, badRequest("Amount and tendered cannot be negative")
+	}
+	if payment.Tendered != 0 && payment.Method != "cash" {
+		return payment, badRequest("Tendered is only used for cash payments")
+	}
+
+	balance := invoice.TotalAmount - invoice.AmountPaid
+	due := balance
+	if payment.Method == "cash" {
+		due = cashDue(balance)
+	}
+	writeOff := payment.Method == "cash" && payment.Amount == 0 && balance > 0 && due == 0
+	if payment.Amount == 0 && payment.Tendered > 0 && !writeOff {
+		if due <= 0 {
+			return payment, conflict("Invoice %s has no balance left to pay", invoice.InvoiceNumber)
+		}
+		payment.Amount = min(payment.Tendered, due)
+	}
+	if payment.Amount == 0 && !writeOff {
+		return payment, badRequest("Amount must be greater than 0")
+	}
+	if payment.Tendered > 0 {
+		if payment.Tendered < payment.Amount {
+			return payment, badRequest("Tendered %s is less than the amount %s", payment.Tendered, payment.Amount)
+		}
+		payment.Change = payment.Tendered - payment.Amount
+	}
+
+	sessionID, err := findCashSession(tx, req.CashSessionID, payment.ReceivedBy)
+	if err != nil {
+		return payment, err
+	}
+	payment.CashSessionID = sessionID
+
+	if writeOff {
+		// Any cash tendered already went back as change
+		payment.Method = "cash_rounding"
+		payment.Amount = balance
+		payment.Reference = fmt.Sprintf("Balance below cash rounding to %s", cashRoundingIncrement())
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		return payment, err
+	}
+
+	if payment.Method == "cash" && due != balance && payment.Amount == due {
+		payment.Rounding = &models.Payment{
+			InvoiceID:     invoice.ID,
+			Method:        "cash_rounding",
+			Amount:        balance - due,
+			Reference:     fmt.Sprintf("Cash rounded to %s", cashRoundingIncrement()),
+			RoundingForID: &payment.ID,
+			ReceivedBy:    payment.ReceivedBy,
+			CashSessionID: payment.CashSessionID,
+			PaidAt:        payment.PaidAt,
+		}
+		if err := tx.Create(payment.Rounding).Error; err != nil {
+			return payment, err
+		}
+	}
+	return payment, refreshPaymentStatus(tx, invoice)
+}
+
+// reversePayment marks a payment as not received (card declined, entered
+// twice, ...) and updates its invoice's payment status
+func reversePayment(tx *gorm.DB, payment *models.Payment, reversedBy, reason string) error {
+	if payment.Reversed {
+		return conflict("Payment %d is already reversed", payment.ID)
+	}
+	if payment.RoundingForID != nil {
+		return conflict("Payment %d is a cash rounding; reverse cash payment %d instead", payment.ID, *payment.RoundingForID)
+	}
+	if strings.TrimSpace(reason) == "" {
+		return badRequest("A reversal reason is required")
+	}
+
+	invoice, err := lockInvoice(tx, "id = ?", payment.InvoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice == nil {
+		return notFound("Invoice not found")
+	}
+	if invoice.RefundedAmount != 0 || invoice.PaymentStatus == "refunded" {
+		return conflict("Invoice %s has been refunded; its payments can no longer be reversed", invoice.InvoiceNumber)
+	}
+
+	now := time.Now()
+	payment.Reversed = true
+	payment.ReversedAt = &now
+	payment.ReversedBy = strings.TrimSpace(reversedBy)
+	payment.ReversalReason = strings.TrimSpace(reason)
+	// The cash payment's rounding goes with it
+	if err := tx.Model(&models.Payment{}).
+		Where("(id = ? OR rounding_for_id = ?) AND reversed = ?", payment.ID, payment.ID, false).
+		Updates(map[string]interface{}{
+			"reversed":        true,
+			"reversed_at":     payment.ReversedAt,
+			"reversed_by":     payment.ReversedBy,
+			"reversal_reason": payment.ReversalReason,
+		}).Error; err != nil {
+		return err
+	}
+	return refreshPaymentStatus(tx, invoice)
+}
+
+// refreshPaymentStatus sums an invoice's payments and derives its status:
+// nothing paid is pending (or stays overdue), less than the total is
+// partially_paid, the total is paid and more is overpaid. Cancelled and
+// refunded invoices keep their status.
+func refreshPaymentStatus(tx *gorm.DB, invoice *models.Invoice) error {
+	var paid models.Money
+	if err := tx.Model(&models.Payment{}).
+		Where("invoice_id = ? AND reversed = ?", invoice.ID, false).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&paid).Error; err != nil {
+		return err
+	}
+
+	status := invoice.PaymentStatus
+	switch {
+	case status == "cancelled" || status == "refunded":
+	case paid <= 0:
+		if status != "overdue" {
+			status = "pending"
+		}
+	case paid < invoice.TotalAmount:
+		status = "partially_paid"
+	case paid == invoice.TotalAmount:
+		status = "paid"
+	default:
+		status = "overpaid"
+	}
+
+	updates := map[string]interface{}{
+		"amount_paid":    paid,
+		"payment_status": status,
+	}
+	if paidStatuses[status] && invoice.PaymentDate == nil {
+		now := time.Now()
+		invoice.PaymentDate = &now
+		updates["payment_date"] = invoice.PaymentDate
+	} else if !paidStatuses[status] && status != "refunded" && invoice.PaymentDate != nil {
+		invoice.PaymentDate = nil
+		updates["payment_date"] = nil
+	}
+
+	invoice.AmountPaid = paid
+	invoice.PaymentStatus = status
+	return tx.Model(invoice).Updates(updates).Error
+}
@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"main/models"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RecordPaymentRequest struct {
+	Method     string       `json:"method" binding:"required"` // cash, card, bank_transfer, voucher
+	Amount     models.Money `json:"amount"`                    // For cash, may be left out when tendered is given
+	Tendered   models.Money `json:"tendered"`                  // Cash handed over by the customer
+	Reference  string       `json:"reference"`
+	ReceivedBy string       `json:"received_by"`
+
+	CashSessionID *uint `json:"cash_session_id"` // Drawer session, defaults to received_by's open session
+}
+
+type ReversePaymentRequest struct {
+	Reason     string `json:"reason" binding:"required"`
+	ReversedBy string `json:"reversed_by"`
+}
+
+// PaymentResponse is a payment together with the state of its invoice afterwards
+type PaymentResponse struct {
+	models.Payment
+	PaymentStatus string       `json:"payment_status"`
+	InvoiceTotal  models.Money `json:"invoice_total"`
+	AmountPaid    models.Money `json:"amount_paid"`
+	BalanceDue    models.Money `json:"balance_due"` // Negative when the invoice is overpaid
+}
+
+// CashDueResponse tells the cashier what to collect in cash and what to give back
+type CashDueResponse struct {
+	InvoiceTotal models.Money `json:"invoice_total"`
+	AmountPaid   models.Money `json:"amount_paid"`
+	BalanceDue   models.Money `json:"balance_due"`
+	Rounding     models.Money `json:"rounding"` // CashDue - BalanceDue
+	CashDue      models.Money `json:"cash_due"`
+	Tendered     models.Money `json:"tendered"`
+	Change       models.Money `json:"change"`
+}
+
+func RecordPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/invoices/%s/payments called", id)
+
+	invoiceID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid invoice ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req RecordPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var result PaymentResponse
+	err = db.Transaction(func(tx *gorm.DB) error {
+		invoice, err := lockInvoice(tx, "id = ?", uint(invoiceID))
+		if err != nil {
+			return err
+		}
+		if invoice == nil {
+			return notFound("Invoice not found")
+		}
+		payment, err := recordPayment(tx, invoice, req)
+		if err != nil {
+			return err
+		}
+		result = newPaymentResponse(payment, invoice)
+		return nil
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to record payment")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Payment recorded successfully",
+		Data:    result,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func GetInvoicePayments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/invoices/%s/payments called", id)
+
+	invoiceID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid invoice ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var payments []models.Payment
+	if err := db.Where("invoice_id = ?", uint(invoiceID)).Order("paid_at, id").Find(&payments).Error; err != nil {
+		log.Printf("Error fetching payments: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve payments",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Payments retrieved successfully",
+		Data:    payments,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func ReversePayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/payments/%s/reverse called", id)
+
+	paymentID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid payment ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req ReversePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var result PaymentResponse
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var payment models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, uint(paymentID)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return notFound("Payment not found")
+			}
+			return err
+		}
+		if err := reversePayment(tx, &payment, req.ReversedBy, req.Reason); err != nil {
+			return err
+		}
+
+		var invoice models.Invoice
+		if err := tx.First(&invoice, payment.InvoiceID).Error; err != nil {
+			return err
+		}
+		result = newPaymentResponse(payment, &invoice)
+		return nil
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to reverse payment")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Payment reversed successfully",
+		Data:    result,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetCashDue(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("GET /api/invoices/%s/cash-due called", id)
+
+	invoiceID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid invoice ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var tendered models.Money
+	if value := r.URL.Query().Get("tendered"); value != "" {
+		if tendered, err = models.ParseMoney(value); err != nil || tendered < 0 {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Invalid tendered amount",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusBadRequest, response)
+			return
+		}
+	}
+
+	var invoice models.Invoice
+	if err := db.First(&invoice, uint(invoiceID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Invoice not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching invoice: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve invoice",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	balance := invoice.TotalAmount - invoice.AmountPaid
+	due := cashDue(balance)
+	result := CashDueResponse{
+		InvoiceTotal: invoice.TotalAmount,
+		AmountPaid:   invoice.AmountPaid,
+		BalanceDue:   balance,
+		Rounding:     due - balance,
+		CashDue:      due,
+		Tendered:     tendered,
+	}
+	if due >= 0 && tendered > due {
+		result.Change = tendered - due
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Cash due calculated successfully",
+		Data:    result,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func newPaymentResponse(payment models.Payment, invoice *models.Invoice) PaymentResponse {
+	return PaymentResponse{
+		Payment:       payment,
+		PaymentStatus: invoice.PaymentStatus,
+		InvoiceTotal:  invoice.TotalAmount,
+		AmountPaid:    invoice.AmountPaid,
+		BalanceDue:    invoice.TotalAmount - invoice.AmountPaid,
+	}
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// reportPeriod is the date range a report covers, in the shop's timezone.
+// Start is inclusive and End exclusive so it can be used as-is in queries.
+type reportPeriod struct {
+	From     string         `json:"from"` // YYYY-MM-DD, inclusive
+	To       string         `json:"to"`   // YYYY-MM-DD, inclusive
+	Timezone string         `json:"timezone"`
+	Start    time.Time      `json:"start"`
+	End      time.Time      `json:"end"`
+	Location *time.Location `json:"-"`
+}
+
+// shopLocation returns the timezone business days are counted in: the tz
+// query parameter, else SHOP_TIMEZONE, else the server's local time.
+func shopLocation(name string) (*time.Location, error) {
+	if name == "" {
+		name = os.Getenv("SHOP_TIMEZONE")
+	}
+	if name == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, badRequest("Unknown timezone %q", name)
+	}
+	return location, nil
+}
+
+// parseReportPeriod reads the from, to and tz query parameters. Both dates
+// default to today; a single date covers that day only.
+func parseReportPeriod(r *http.Request) (reportPeriod, error) {
+	query := r.URL.Query()
+	var period reportPeriod
+
+	location, err := shopLocation(query.Get("tz"))
+	if err != nil {
+		return period, err
+	}
+
+	now := time.Now().In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	from, to := today, today
+
+	if value := query.Get("from"); value != "" {
+		if from, err = time.ParseInLocation("2006-01-02", value, location); err != nil {
+			return period, badRequest("Invalid from date %q, expected YYYY-MM-DD", value)
+		}
+		to = from
+	}
+	if value := query.Get("to"); value != "" {
+		if to, err = time.ParseInLocation("2006-01-02", value, location); err != nil {
+			return period, badRequest("Invalid to date %q, expected YYYY-MM-DD", value)
+		}
+		if query.Get("from") == "" {
+			from = to
+		}
+	}
+	if to.Before(from) {
+		return period, badRequest("to must not be before from")
+	}
+	if to.Sub(from) > 366*24*time.Hour {
+		return period, badRequest("Date range cannot exceed one year")
+	}
+
+	return newReportPeriod(from, to.AddDate(0, 0, 1), location), nil
+}
+
+// newReportPeriod builds a period from its start and exclusive end
+func newReportPeriod(start, end time.Time, location *time.Location) reportPeriod {
+	return reportPeriod{
+		From:     start.Format("2006-01-02"),
+		To:       end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Timezone: location.String(),
+		Start:    start,
+		End:      end,
+		Location: location,
+	}
+}
+
+// sqlTimezone names the period's timezone for PostgreSQL's AT TIME ZONE. The
+// server's local zone has no IANA name, so its current offset is used as a
+// POSIX zone, whose sign is inverted.
+func (p reportPeriod) sqlTimezone() string {
+	if p.Location != time.Local {
+		return p.Location.String()
+	}
+	_, offset := time.Now().In(p.Location).Zone()
+	sign := "-"
+	if offset < 0 {
+		sign = "+"
+		offset = -offset
+	}
+	return fmt.Sprintf("SHOP%s%02d:%02d", sign, offset/3600, offset%3600/60)
+}
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"main/models"
+
+	"gorm.io/gorm"
+)
+
+// priceMismatchPolicy returns how client prices that disagree with the menu are handled:
+// "reject" (default) fails the order, "flag" bills the menu price and marks the line.
+func priceMismatchPolicy() string {
+	if strings.ToLower(os.Getenv("PRICE_MISMATCH_POLICY")) == "flag" {
+		return "flag"
+	}
+	return "reject"
+}
+
+// linePrice is the menu side of an order line: the item, the variant it
+// refers to and the price and description to snapshot onto the OrderItem.
+type linePrice struct {
+	Item        models.Item
+	PizzaID     *uint
+	BeverageID  *uint
+	ToppingID   *uint
+	UnitPrice   models.Money
+	Description string
+}
+
+// lookupLinePrice resolves the active item for an order line together with its
+// current menu price. Lines that name a pizza, beverage or topping variant are
+// priced from that variant, otherwise from Item.UnitPrice.
+func lookupLinePrice(tx *gorm.DB, line CreateOrderItemRequest) (linePrice, error) {
+	var price linePrice
+	if err := tx.Where("id = ? AND is_active = ?", line.ItemID, true).First(&price.Item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return price, badRequest("Item with ID %d not found", line.ItemID)
+		}
+		return price, err
+	}
+	item := price.Item
+
+	variants := 0
+	for _, id := range []*uint{line.PizzaID, line.BeverageID, line.ToppingID} {
+		if id != nil {
+			variants++
+		}
+	}
+	if variants > 1 {
+		return price, badRequest("Only one of pizza_id, beverage_id or topping_id may be set for item %d", line.ItemID)
+	}
+
+	switch {
+	case line.PizzaID != nil:
+		var pizza models.Pizza
+		if err := tx.Where("id = ? AND item_id = ? AND is_active = ?", *line.PizzaID, item.ID, true).First(&pizza).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return price, badRequest("Pizza with ID %d not found for item %d", *line.PizzaID, item.ID)
+			}
+			return price, err
+		}
+		price.PizzaID = &pizza.ID
+		price.UnitPrice = pizza.Price
+		price.Description = pizzaDescription(pizza)
+
+	case line.BeverageID != nil:
+		var beverage models.Beverage
+		if err := tx.Where("id = ? AND item_id = ? AND is_active = ?", *line.BeverageID, item.ID, true).First(&beverage).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return price, badRequest("Beverage with ID %d not found for item %d", *line.BeverageID, item.ID)
+			}
+			return price, err
+		}
+		price.BeverageID = &beverage.ID
+		price.UnitPrice = beverage.Price
+		price.Description = strings.TrimSpace(beverage.Name + " " + beverage.Size)
+
+	case line.ToppingID != nil:
+		// Toppings sold as their own line are billed against their topping item
+		if item.Type != "topping" {
+			return price, badRequest("topping_id can only be used with topping items, item %d is a %s", item.ID, item.Type)
+		}
+		var topping models.Topping
+		if err := tx.Where("id = ? AND item_id = ? AND is_active = ?", *line.ToppingID, item.ID, true).First(&topping).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return price, badRequest("Topping with ID %d not found for item %d", *line.ToppingID, item.ID)
+			}
+			return price, err
+		}
+		price.ToppingID = &topping.ID
+		price.UnitPrice = topping.Price
+		price.Description = topping.Name
+
+	default:
+		price.UnitPrice = item.UnitPrice
+		price.Description = item.Name
+	}
+
+	return price, nil
+}
+
+// pizzaDescription renders a pizza variant the way it is read out at the counter,
+// e.g. "Large thin crust Margherita".
+func pizzaDescription(pizza models.Pizza) string {
+	parts := []string{}
+	if pizza.Size != "" {
+		parts = append(parts, strings.ToUpper(pizza.Size[:1])+pizza.Size[1:])
+	}
+	if pizza.BaseType != "" {
+		parts = append(parts, pizza.BaseType+" crust")
+	}
+	parts = append(parts, pizza.Name)
+	return strings.Join(parts, " ")
+}
+
+// buildOrderItem prices an order line from the menu and returns the row to
+// persist (without OrderID). A client-supplied price that disagrees with the
+// menu is rejected or flagged according to PRICE_MISMATCH_POLICY.
+func buildOrderItem(tx *gorm.DB, line CreateOrderItemRequest) (models.OrderItem, error) {
+	price, err := lookupLinePrice(tx, line)
+	if err != nil {
+		return models.OrderItem{}, err
+	}
+	unitPrice := price.UnitPrice
+
+	toppings, err := buildOrderItemToppings(tx, price.Item, line)
+	if err != nil {
+		return models.OrderItem{}, err
+	}
+	var toppingsPrice models.Money
+	for _, topping := range toppings {
+		toppingsPrice += topping.TotalPrice
+	}
+
+	totalPrice, err := (unitPrice + toppingsPrice).Times(line.Quantity)
+	if err != nil {
+		return models.OrderItem{}, badRequest("Total of item %d is too large: %v", line.ItemID, err)
+	}
+
+	orderItem := models.OrderItem{
+		ItemID:        line.ItemID,
+		PizzaID:       price.PizzaID,
+		BeverageID:    price.BeverageID,
+		ToppingID:     price.ToppingID,
+		Description:   price.Description,
+		Quantity:      line.Quantity,
+		UnitPrice:     unitPrice,
+		ToppingsPrice: toppingsPrice,
+		TotalPrice:    totalPrice,
+		Toppings:      toppings,
+	}
+
+	if line.Price != nil && *line.Price != unitPrice {
+		if priceMismatchPolicy() == "reject" {
+			return orderItem, &requestError{
+				Status:  http.StatusConflict,
+				Message: fmt.Sprintf("Price %s for item %d does not match current price %s", *line.Price, line.ItemID, unitPrice),
+			}
+		}
+		clientPrice := *line.Price
+		orderItem.ClientUnitPrice = &clientPrice
+		orderItem.PriceMismatch = true
+	}
+
+	return orderItem, nil
+}
+
+// buildOrderItemToppings prices the topping modifiers of a pizza line. Extra
+// toppings are charged at Topping.Price per portion, removed ones are free.
+func buildOrderItemToppings(tx *gorm.DB, item models.Item, line CreateOrderItemRequest) ([]models.OrderItemTopping, error) {
+	if len(line.Toppings) == 0 {
+		return nil, nil
+	}
+	if item.Type != "pizza" {
+		return nil, badRequest("Toppings can only be added to pizza items, item %d is a %s", item.ID, item.Type)
+	}
+
+	seen := map[uint]bool{}
+	toppings := make([]models.OrderItemTopping, 0, len(line.Toppings))
+	for _, req := range line.Toppings {
+		action := strings.ToLower(req.Action)
+		if action == "" {
+			action = "add"
+		}
+		if action != "add" && action != "remove" {
+			return nil, badRequest("Invalid topping action %q for item %d. Must be add or remove", req.Action, item.ID)
+		}
+
+		quantity := req.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		if quantity < 0 || quantity > maxLineQuantity || (action == "remove" && quantity != 1) {
+			return nil, badRequest("Invalid quantity %d for topping %d", req.Quantity, req.ToppingID)
+		}
+
+		if seen[req.ToppingID] {
+			return nil, badRequest("Topping %d is listed more than once for item %d", req.ToppingID, item.ID)
+		}
+		seen[req.ToppingID] = true
+
+		var topping models.Topping
+		if err := tx.Where("id = ? AND is_active = ?", req.ToppingID, true).First(&topping).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, badRequest("Topping with ID %d not found", req.ToppingID)
+			}
+			return nil, err
+		}
+
+		modifier := models.OrderItemTopping{
+			ToppingID: topping.ID,
+			Name:      topping.Name,
+			Action:    action,
+			Quantity:  quantity,
+		}
+		if action == "add" {
+			modifier.UnitPrice = topping.Price
+			total, err := topping.Price.Times(quantity)
+			if err != nil {
+				return nil, badRequest("Price of topping %d is too large: %v", topping.ID, err)
+			}
+			modifier.TotalPrice = total
+		}
+		toppings = append(toppings, modifier)
+	}
+
+	return toppings, nil
+}
+
+// preloadOrderItems loads order lines with the catalog rows they reference
+func preloadOrderItems(query *gorm.DB, prefix string) *gorm.DB {
+	return query.Preload(prefix + "OrderItems").
+		Preload(prefix + "OrderItems.Item").
+		Preload(prefix + "OrderItems.Pizza").
+		Preload(prefix + "OrderItems.Beverage").
+		Preload(prefix + "OrderItems.Topping").
+		Preload(prefix + "OrderItems.Toppings")
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/models"
+	"main/receipt"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type CreatePrintJobRequest struct {
+	Printer string `json:"printer"` // Configured printer name, "default" when empty
+	Columns int    `json:"columns"` // 42 or 48, PRINTER_COLUMNS when empty
+	QRCode  bool   `json:"qr_code"` // Print the invoice number as a QR code
+}
+
+const printerTimeout = 5 * time.Second
+
+// CreatePrintJob renders an invoice as ESC/POS and sends it to a configured thermal printer
+func CreatePrintJob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("POST /api/invoices/%s/print-jobs called", id)
+
+	invoiceID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid invoice ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req CreatePrintJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if req.Printer == "" {
+		req.Printer = "default"
+	}
+	if req.Columns == 0 {
+		req.Columns = receipt.ColumnsFromEnv()
+	}
+
+	// Only configured printers can be targeted, so the API cannot write to arbitrary hosts or files
+	target, ok := receipt.PrintersFromEnv()[req.Printer]
+	if !ok {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Printer " + strconv.Quote(req.Printer) + " is not configured",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var invoice models.Invoice
+	if err := preloadInvoice(db).First(&invoice, invoiceID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Invoice not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching invoice: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve invoice",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	data, err := receipt.RenderESCPOS(newReceipt(invoice), receipt.ESCPOSOptions{Columns: req.Columns, QRCode: req.QRCode})
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: err.Error(),
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	job := models.PrintJob{
+		InvoiceID: invoice.ID,
+		Printer:   req.Printer,
+		Target:    target,
+		Columns:   req.Columns,
+		Bytes:     len(data),
+		Status:    "sent",
+	}
+	sendErr := receipt.Send(target, data, printerTimeout)
+	if sendErr != nil {
+		log.Printf("Error printing invoice %d on %s: %v", invoice.ID, target, sendErr)
+		job.Status = "failed"
+		job.Error = sendErr.Error()
+	}
+
+	if err := db.Create(&job).Error; err != nil {
+		log.Printf("Error recording print job: %v", err)
+	}
+
+	if sendErr != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to print invoice: " + sendErr.Error(),
+			Data:    job,
+		}
+		utils.SendJSONResponse(w, http.StatusBadGateway, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Invoice sent to printer successfully",
+		Data:    job,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+// Helper function to parse the escpos print options from query parameters
+func escposOptions(r *http.Request) receipt.ESCPOSOptions {
+	opts := receipt.ESCPOSOptions{Columns: receipt.ColumnsFromEnv()}
+	if columns, err := strconv.Atoi(r.URL.Query().Get("columns")); err == nil {
+		opts.Columns = columns
+	}
+	opts.QRCode = strings.EqualFold(r.URL.Query().Get("qr"), "true")
+	return opts
+}
@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"main/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// validRefundMethods are the ways money can go back to a customer
+var validRefundMethods = map[string]bool{
+	"cash":          true,
+	"card":          true,
+	"bank_transfer": true,
+	"voucher":       true,
+}
+
+// creditedLine is what earlier credit notes already refunded of one order line,
+// with positive amounts
+type creditedLine struct {
+	Quantity int
+	Net      models.Money
+	Tax      models.Money
+}
+
+// lockInvoice loads an invoice and locks its row until the transaction ends
+func lockInvoice(tx *gorm.DB, query string, args ...interface{}) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(query, args...).First(&invoice).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// cancelOrder cancels an order and settles its invoice: an unpaid invoice is
+// voided, a paid one is refunded in full with a credit note (returned).
+func cancelOrder(tx *gorm.DB, order *models.Order, req CancelOrderRequest) (*models.CreditNote, error) {
+	if err := transitionOrderStatus(tx, order, "cancelled", req.ChangedBy, req.Reason); err != nil {
+		return nil, err
+	}
+
+	invoice, err := lockInvoice(tx, "order_id = ?", order.ID)
+	if err != nil || invoice == nil {
+		return nil, err
+	}
+
+	switch invoice.PaymentStatus {
+	case "paid", "overpaid":
+		if req.RefundMethod == "" {
+			return nil, conflict("Invoice %s is paid; a refund_method is required to cancel the order", invoice.InvoiceNumber)
+		}
+		creditNote, err := issueCreditNote(tx, invoice, CreateCreditNoteRequest{
+			RefundMethod:  req.RefundMethod,
+			Reason:        req.Reason,
+			CreatedBy:     req.ChangedBy,
+			CashSessionID: req.CashSessionID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &creditNote, nil
+
+	case "refunded", "cancelled":
+		return nil, nil
+
+	case "partially_paid":
+		return nil, conflict("Invoice %s is partially paid; reverse its payments before cancelling the order", invoice.InvoiceNumber)
+
+	default:
+		now := time.Now()
+		return nil, tx.Model(invoice).Updates(map[string]interface{}{
+			"payment_status": "cancelled",
+			"voided_at":      &now,
+			"void_reason":    strings.TrimSpace(req.Reason),
+		}).Error
+	}
+}
+
+// issueCreditNote refunds lines of a paid invoice, which must be locked by the
+// caller. Each line names an order item and how many units to refund; no lines
+// means everything not refunded yet. Refunds belong to the given cash session,
+// else to the open session of the staff member issuing them; cash refunds are
+// paid from its drawer and rounded to the cash rounding increment like cash
+// payments. The last
+// units of a line take whatever is left of its amounts, so refunding a line in
+// parts adds up exactly.
+func issueCreditNote(tx *gorm.DB, invoice *models.Invoice, req CreateCreditNoteRequest) (models.CreditNote, error) {
+	creditNote := models.CreditNote{
+		InvoiceID:      invoice.ID,
+		CreditNoteDate: time.Now(),
+		RefundMethod:   strings.ToLower(strings.TrimSpace(req.RefundMethod)),
+		Reason:         strings.TrimSpace(req.Reason),
+		CreatedBy:      strings.TrimSpace(req.CreatedBy),
+	}
+	lines := req.Lines
+
+	if !paidStatuses[invoice.PaymentStatus] {
+		return creditNote, conflict("Invoice %s is %s; only paid invoices can be refunded", invoice.InvoiceNumber, invoice.PaymentStatus)
+	}
+	if !validRefundMethods[creditNote.RefundMethod] {
+		return creditNote, badRequest("Invalid refund method %q. Must be one of: cash, card, bank_transfer, voucher", req.RefundMethod)
+	}
+	if creditNote.Reason == "" {
+		return creditNote, badRequest("A refund reason is required")
+	}
+
+	var orderItems []models.OrderItem
+	if err := tx.Where("order_id = ?", invoice.OrderID).Order("id").Find(&orderItems).Error; err != nil {
+		return creditNote, err
+	}
+	credited, err := creditedLines(tx, invoice.ID)
+	if err != nil {
+		return creditNote, err
+	}
+
+	requested := map[uint]int{}
+	if len(lines) == 0 {
+		for _, orderItem := range orderItems {
+			if left := orderItem.Quantity - credited[orderItem.ID].Quantity; left > 0 {
+				requested[orderItem.ID] = left
+			}
+		}
+		if len(requested) == 0 {
+			return creditNote, conflict("Invoice %s has already been refunded in full", invoice.InvoiceNumber)
+		}
+	}
+	onInvoice := map[uint]bool{}
+	for _, orderItem := range orderItems {
+		onInvoice[orderItem.ID] = true
+	}
+	for _, line := range lines {
+		if !onInvoice[line.OrderItemID] {
+			return creditNote, badRequest("Order item %d is not on invoice %s", line.OrderItemID, invoice.InvoiceNumber)
+		}
+		if line.Quantity <= 0 {
+			return creditNote, badRequest("Quantity must be greater than 0 for order item %d", line.OrderItemID)
+		}
+		if _, ok := requested[line.OrderItemID]; ok {
+			return creditNote, badRequest("Order item %d is listed more than once", line.OrderItemID)
+		}
+		requested[line.OrderItemID] = line.Quantity
+	}
+
+	var net, tax models.Money
+	refundedAll := true
+	for _, orderItem := range orderItems {
+		quantity, ok := requested[orderItem.ID]
+		previous := credited[orderItem.ID]
+		left := orderItem.Quantity - previous.Quantity
+		if quantity > left {
+			return creditNote, badRequest("Only %d of order item %d can still be refunded", left, orderItem.ID)
+		}
+		if quantity < left {
+			refundedAll = false
+		}
+		if !ok {
+			continue
+		}
+
+		line, err := creditNoteLine(orderItem, previous, quantity)
+		if err != nil {
+			return creditNote, err
+		}
+
+		net += line.NetAmount
+		tax += line.TaxAmount
+		creditNote.Lines = append(creditNote.Lines, line)
+	}
+	creditNote.SubtotalAmount = net
+	creditNote.TaxAmount = tax
+	creditNote.TotalAmount = net + tax
+
+	var invoiceTaxes []models.InvoiceTax
+	if err := tx.Where("invoice_id = ?", invoice.ID).Order("id").Find(&invoiceTaxes).Error; err != nil {
+		return creditNote, err
+	}
+	creditNote.Taxes = creditNoteTaxes(invoiceTaxes, invoice, net, tax)
+
+	// Like payments, refunds of every method belong to a session, so its Z
+	// report lists them all
+	if creditNote.CashSessionID, err = findCashSession(tx, req.CashSessionID, creditNote.CreatedBy); err != nil {
+		return creditNote, err
+	}
+	if creditNote.RefundMethod == "cash" {
+		// The customer paid the rounded amount in cash, so they get a rounded
+		// amount back; the invoice is still credited the full total
+		creditNote.CashRounding = creditNote.TotalAmount + cashDue(-creditNote.TotalAmount)
+	}
+
+	number, err := nextDocumentNumber(tx, creditNoteSeries, creditNote.CreditNoteDate)
+	if err != nil {
+		return creditNote, err
+	}
+	creditNote.CreditNoteNumber = number
+	if err := tx.Create(&creditNote).Error; err != nil {
+		return creditNote, err
+	}
+
+	invoice.RefundedAmount -= creditNote.TotalAmount
+	updates := map[string]interface{}{"refunded_amount": invoice.RefundedAmount}
+	if refundedAll {
+		invoice.PaymentStatus = "refunded"
+		updates["payment_status"] = invoice.PaymentStatus
+	}
+	return creditNote, tx.Model(invoice).Updates(updates).Error
+}
+
+// creditNoteLine refunds quantity units of an order line, of which previous
+// was already refunded. The last units take whatever is left of the line's
+// amounts. A line with a total but no net or tax amount predates lines
+// carrying tax; refunding it would credit nothing, so it is refused.
+func creditNoteLine(orderItem models.OrderItem, previous creditedLine, quantity int) (models.CreditNoteLine, error) {
+	line := models.CreditNoteLine{
+		OrderItemID: orderItem.ID,
+		Description: orderItem.Description,
+		Quantity:    quantity,
+		UnitPrice:   -(orderItem.UnitPrice + orderItem.ToppingsPrice),
+	}
+	if orderItem.NetAmount+orderItem.TaxAmount == 0 && orderItem.TotalPrice != 0 {
+		return line, conflict("Order item %d has no net and tax amounts recorded and cannot be refunded", orderItem.ID)
+	}
+
+	if quantity == orderItem.Quantity-previous.Quantity {
+		line.NetAmount = -(orderItem.NetAmount - previous.Net)
+		line.TaxAmount = -(orderItem.TaxAmount - previous.Tax)
+	} else {
+		line.NetAmount = -orderItem.NetAmount.Prorate(int64(quantity), int64(orderItem.Quantity))
+		line.TaxAmount = -orderItem.TaxAmount.Prorate(int64(quantity), int64(orderItem.Quantity))
+	}
+	line.TotalAmount = line.NetAmount + line.TaxAmount
+	return line, nil
+}
+
+// creditedLines sums what earlier credit notes of an invoice refunded per order item
+func creditedLines(tx *gorm.DB, invoiceID uint) (map[uint]creditedLine, error) {
+	var lines []models.CreditNoteLine
+	err := tx.Joins("JOIN credit_notes ON credit_notes.id = credit_note_lines.credit_note_id").
+		Where("credit_notes.invoice_id = ? AND credit_notes.deleted_at IS NULL", invoiceID).
+		Find(&lines).Error
+	if err != nil {
+		return nil, err
+	}
+
+	credited := map[uint]creditedLine{}
+	for _, line := range lines {
+		sum := credited[line.OrderItemID]
+		sum.Quantity += line.Quantity
+		sum.Net -= line.NetAmount
+		sum.Tax -= line.TaxAmount
+		credited[line.OrderItemID] = sum
+	}
+	return credited, nil
+}
+
+// creditNoteTaxes splits a credit note's (negative) tax over the invoice's tax
+// rates in proportion to what each rate charged. The last rate takes the
+// rounding remainder so the rows add up to the credit note's tax.
+func creditNoteTaxes(invoiceTaxes []models.InvoiceTax, invoice *models.Invoice, net, tax models.Money) []models.CreditNoteTax {
+	taxes := make([]models.CreditNoteTax, 0, len(invoiceTaxes))
+	taxLeft := tax
+	for i, invoiceTax := range invoiceTaxes {
+		row := models.CreditNoteTax{
+			TaxRateID: invoiceTax.TaxRateID,
+			Code:      invoiceTax.Code,
+			Name:      invoiceTax.Name,
+			Rate:      invoiceTax.Rate,
+			Inclusive: invoiceTax.Inclusive,
+		}
+		if invoice.SubtotalAmount != 0 {
+			row.TaxableAmount = invoiceTax.TaxableAmount.Prorate(int64(net), int64(invoice.SubtotalAmount))
+		}
+		if i == len(invoiceTaxes)-1 {
+			row.TaxAmount = taxLeft
+		} else if invoice.TaxAmount != 0 {
+			row.TaxAmount = invoiceTax.TaxAmount.Prorate(int64(tax), int64(invoice.TaxAmount))
+		}
+		taxLeft -= row.TaxAmount
+		taxes = append(taxes, row)
+	}
+	return taxes
+}
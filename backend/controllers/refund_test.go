@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"main/models"
+)
+
+func TestCreditNoteLine(t *testing.T) {
+	// 3 x 11.80 with 18% VAT included: 30.00 net, 5.40 tax
+	orderItem := models.OrderItem{
+		ID:         7,
+		Quantity:   3,
+		UnitPrice:  money(t, "11.80"),
+		TotalPrice: money(t, "35.40"),
+		NetAmount:  money(t, "30.00"),
+		TaxAmount:  money(t, "5.40"),
+	}
+
+	tests := []struct {
+		name     string
+		previous creditedLine
+		quantity int
+		net      string
+		tax      string
+	}{
+		{"everything", creditedLine{}, 3, "-30.00", "-5.40"},
+		{"one unit", creditedLine{}, 1, "-10.00", "-1.80"},
+		{"last units take the rest", creditedLine{Quantity: 1, Net: money(t, "10.00"), Tax: money(t, "1.79")}, 2, "-20.00", "-3.61"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := creditNoteLine(orderItem, tt.previous, tt.quantity)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line.NetAmount != money(t, tt.net) || line.TaxAmount != money(t, tt.tax) {
+				t.Errorf("got net %s, tax %s; want %s, %s", line.NetAmount, line.TaxAmount, tt.net, tt.tax)
+			}
+			if line.TotalAmount != line.NetAmount+line.TaxAmount {
+				t.Errorf("total %s is not net + tax", line.TotalAmount)
+			}
+			if line.UnitPrice != -orderItem.UnitPrice {
+				t.Errorf("unit price %s, want %s", line.UnitPrice, -orderItem.UnitPrice)
+			}
+		})
+	}
+}
+
+// Lines from before lines carried tax would be credited 0.00 while the
+// invoice is marked refunded
+func TestCreditNoteLineWithoutTaxSplit(t *testing.T) {
+	legacy := models.OrderItem{ID: 8, Quantity: 2, TotalPrice: money(t, "25.00")}
+	_, err := creditNoteLine(legacy, creditedLine{}, 2)
+	var reqErr *requestError
+	if !errors.As(err, &reqErr) || reqErr.Status != http.StatusConflict {
+		t.Fatalf("got error %v, want a conflict", err)
+	}
+
+	// A free line has nothing to credit and is fine
+	free := models.OrderItem{ID: 9, Quantity: 1}
+	if line, err := creditNoteLine(free, creditedLine{}, 1); err != nil || line.TotalAmount != 0 {
+		t.Errorf("free line: got %s, %v", line.TotalAmount, err)
+	}
+}
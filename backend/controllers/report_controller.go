@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"main/utils"
+)
+
+func GetSalesReport(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/reports/sales called")
+
+	period, err := parseReportPeriod(r)
+	if err != nil {
+		sendRequestError(w, err, "Failed to build sales report")
+		return
+	}
+
+	statuses, err := parsePaymentStatuses(r.URL.Query().Get("payment_status"))
+	if err != nil {
+		sendRequestError(w, err, "Failed to build sales report")
+		return
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "day"
+	}
+
+	report, err := buildSalesReport(db, period, groupBy, statuses)
+	if err != nil {
+		sendRequestError(w, err, "Failed to build sales report")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		filename := fmt.Sprintf("sales-%s-%s-by-%s.csv", period.From, period.To, groupBy)
+		header := []string{groupBy, "label", "orders", "quantity", "net_sales", "tax", "gross_sales"}
+		utils.SendCSVResponse(w, filename, header, report.csvRows())
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Sales report generated successfully",
+		Data:    report,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetTaxReport(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/reports/tax called")
+
+	period, err := parseFilingPeriod(r)
+	if err != nil {
+		sendRequestError(w, err, "Failed to build tax report")
+		return
+	}
+
+	report, err := buildTaxReport(db, period)
+	if err != nil {
+		sendRequestError(w, err, "Failed to build tax report")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		filename := fmt.Sprintf("tax-%s-%s.csv", period.From, period.To)
+		header := []string{"code", "name", "rate", "inclusive", "taxable_amount", "tax_collected", "tax_refunded", "net_tax"}
+		utils.SendCSVResponse(w, filename, header, report.csvRows())
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Tax report generated successfully",
+		Data:    report,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"main/models"
+
+	"gorm.io/gorm"
+)
+
+// salesGrouping is how a sales report buckets order lines. Key and Label are
+// SQL expressions; localTime in them stands for the order date in the
+// report's timezone.
+type salesGrouping struct {
+	Key   string
+	Label string
+	Order string
+}
+
+const localTime = "(orders.order_date AT TIME ZONE ?)"
+
+var salesGroupings = map[string]salesGrouping{
+	"day": {
+		Key:   "to_char(date_trunc('day', " + localTime + "), 'YYYY-MM-DD')",
+		Label: "to_char(date_trunc('day', " + localTime + "), 'Dy DD Mon YYYY')",
+		Order: "1",
+	},
+	"week": {
+		Key:   "to_char(date_trunc('week', " + localTime + "), 'IYYY-\"W\"IW')",
+		Label: "'Week of ' || to_char(date_trunc('week', " + localTime + "), 'YYYY-MM-DD')",
+		Order: "1",
+	},
+	"month": {
+		Key:   "to_char(date_trunc('month', " + localTime + "), 'YYYY-MM')",
+		Label: "to_char(date_trunc('month', " + localTime + "), 'FMMonth YYYY')",
+		Order: "1",
+	},
+	"hour": {
+		Key:   "to_char(" + localTime + ", 'HH24')",
+		Label: "to_char(" + localTime + ", 'HH24') || ':00'",
+		Order: "1",
+	},
+	"item": {
+		Key:   "order_items.item_id::text",
+		Label: "items.name",
+		Order: "gross_sales DESC",
+	},
+	"item_type": {
+		Key:   "CASE WHEN order_items.topping_id IS NOT NULL THEN 'topping' ELSE items.type END",
+		Label: "CASE WHEN order_items.topping_id IS NOT NULL THEN 'topping' ELSE items.type END",
+		Order: "gross_sales DESC",
+	},
+}
+
+// salesFigures are the aggregates shared by report rows and totals
+const salesFigures = `COUNT(DISTINCT orders.id) AS order_count,
+	COALESCE(SUM(order_items.quantity), 0) AS quantity,
+	COALESCE(SUM(order_items.net_amount), 0) AS net_sales,
+	COALESCE(SUM(order_items.tax_amount), 0) AS tax,
+	COALESCE(SUM(order_items.net_amount + order_items.tax_amount), 0) AS gross_sales`
+
+// Response structures
+type SalesReport struct {
+	Period        reportPeriod     `json:"period"`
+	GroupBy       string           `json:"group_by"`
+	PaymentStatus []string         `json:"payment_status,omitempty"`
+	Rows          []SalesReportRow `json:"rows"`
+	Totals        SalesReportRow   `json:"totals"`
+}
+
+type SalesReportRow struct {
+	Key        string       `json:"key"`
+	Label      string       `json:"label"`
+	OrderCount int64        `json:"order_count"`
+	Quantity   int64        `json:"quantity"`
+	NetSales   models.Money `json:"net_sales"`
+	Tax        models.Money `json:"tax"`
+	GrossSales models.Money `json:"gross_sales"`
+}
+
+// salesLines selects the order lines of orders placed in the period that were
+// not cancelled, optionally only those whose invoice has one of statuses.
+// Delivery fee lines are left out, as on the dashboard.
+func salesLines(tx *gorm.DB, period reportPeriod, statuses []string) *gorm.DB {
+	query := tx.Table("order_items").
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL").
+		Joins("JOIN items ON items.id = order_items.item_id").
+		Where("order_items.deleted_at IS NULL AND NOT order_items.delivery_fee AND orders.order_status <> ?", "cancelled").
+		Where("orders.order_date >= ? AND orders.order_date < ?", period.Start, period.End)
+	if len(statuses) > 0 {
+		query = query.
+			Joins("JOIN invoices ON invoices.order_id = orders.id AND invoices.deleted_at IS NULL").
+			Where("invoices.payment_status IN ?", statuses)
+	}
+	return query
+}
+
+// buildSalesReport aggregates sales for the period by groupBy
+func buildSalesReport(tx *gorm.DB, period reportPeriod, groupBy string, statuses []string) (SalesReport, error) {
+	report := SalesReport{
+		Period:        period,
+		GroupBy:       groupBy,
+		PaymentStatus: statuses,
+		Rows:          []SalesReportRow{},
+	}
+
+	grouping, ok := salesGroupings[groupBy]
+	if !ok {
+		return report, badRequest("Invalid group_by %q. Must be one of: day, week, month, hour, item, item_type", groupBy)
+	}
+
+	var args []interface{}
+	for i := strings.Count(grouping.Key+grouping.Label, "?"); i > 0; i-- {
+		args = append(args, period.sqlTimezone())
+	}
+
+	// Grouping by position keeps the timezone a bound parameter
+	if err := salesLines(tx, period, statuses).
+		Select(fmt.Sprintf("%s AS key, %s AS label, %s", grouping.Key, grouping.Label, salesFigures), args...).
+		Group("1, 2").
+		Order(grouping.Order).
+		Scan(&report.Rows).Error; err != nil {
+		return report, err
+	}
+
+	if err := salesLines(tx, period, statuses).
+		Select("'total' AS key, 'Total' AS label, " + salesFigures).
+		Scan(&report.Totals).Error; err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// parsePaymentStatuses reads a comma separated payment_status filter
+func parsePaymentStatuses(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var statuses []string
+	for _, status := range strings.Split(value, ",") {
+		status = strings.ToLower(strings.TrimSpace(status))
+		if !validPaymentStatuses[status] {
+			return nil, badRequest("Invalid payment_status %q", status)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// csvRows renders the report rows followed by the totals
+func (report SalesReport) csvRows() [][]string {
+	rows := make([][]string, 0, len(report.Rows)+1)
+	for _, row := range append(report.Rows, report.Totals) {
+		rows = append(rows, []string{
+			row.Key,
+			row.Label,
+			fmt.Sprint(row.OrderCount),
+			fmt.Sprint(row.Quantity),
+			row.NetSales.String(),
+			row.Tax.String(),
+			row.GrossSales.String(),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"main/models"
+
+	"gorm.io/gorm"
+)
+
+// taxableLine is one priced amount and the tax rates that apply to it. Amount
+// already contains the inclusive rates and excludes the exclusive ones.
+type taxableLine struct {
+	Amount models.Money
+	Rates  []models.TaxRate
+}
+
+// lineTax splits a taxable line into its net amount and tax
+type lineTax struct {
+	Net models.Money
+	Tax models.Money
+}
+
+// taxBreakdown is the tax charged for one rate
+type taxBreakdown struct {
+	TaxRateID     uint
+	Code          string
+	Name          string
+	Rate          float64
+	Inclusive     bool
+	TaxableAmount models.Money
+	TaxAmount     models.Money
+
+	// Unrounded sums, used when rounding per invoice
+	taxable *big.Rat
+	tax     *big.Rat
+}
+
+// taxResult is the outcome of one tax computation over a set of lines
+type taxResult struct {
+	Lines     []lineTax
+	Subtotal  models.Money // Sum of net amounts
+	Tax       models.Money
+	Total     models.Money // Subtotal + Tax
+	Breakdown []taxBreakdown
+}
+
+// taxRounding returns where tax is rounded to cents: "line" (default) rounds
+// every line and adds the rounded amounts up, "invoice" adds the exact line
+// taxes up per rate and rounds each rate's total once.
+func taxRounding() string {
+	if strings.ToLower(os.Getenv("TAX_ROUNDING")) == "invoice" {
+		return "invoice"
+	}
+	return "line"
+}
+
+// calculateTax is the tax engine. For every line it backs the inclusive rates
+// out of the price to find the net amount, then charges every rate on that net.
+// Amounts are exact until they are rounded (half away from zero) according to
+// TAX_ROUNDING. Either way the inclusive taxes add back up to the prices
+// exactly, so Total is always the sum of the line prices plus exclusive tax,
+// and the line nets and taxes add up to Subtotal and Tax.
+func calculateTax(lines []taxableLine) taxResult {
+	result := taxResult{Lines: make([]lineTax, 0, len(lines))}
+	perLine := taxRounding() == "line"
+	byRate := map[uint]int{}
+	var gross, exclusiveTax, inclusiveTax models.Money
+	exactNets := make([]*big.Rat, 0, len(lines))
+	exactTaxes := make([]*big.Rat, 0, len(lines))
+
+	for _, line := range lines {
+		inclusiveRate := big.NewRat(1, 1)
+		inclusiveCount := 0
+		for _, rate := range line.Rates {
+			if rate.Inclusive {
+				inclusiveRate.Add(inclusiveRate, models.RateRat(rate.Rate))
+				inclusiveCount++
+			}
+		}
+
+		exactNet := new(big.Rat).Quo(line.Amount.Rat(), inclusiveRate)
+		net := models.RoundRat(exactNet)
+		inclusiveLeft := line.Amount - net
+		if perLine {
+			// Charge the rates on the rounded net
+			exactNet = net.Rat()
+		}
+
+		var lineTaxTotal models.Money
+		exactLineTax := new(big.Rat)
+		for _, rate := range line.Rates {
+			exact := models.PercentOf(exactNet, rate.Rate)
+			amount := models.RoundRat(exact)
+			if rate.Inclusive && perLine {
+				// The last inclusive rate takes the rounding remainder
+				inclusiveCount--
+				if inclusiveCount == 0 {
+					amount = inclusiveLeft
+				}
+				inclusiveLeft -= amount
+			}
+			lineTaxTotal += amount
+			exactLineTax.Add(exactLineTax, exact)
+
+			i, ok := byRate[rate.ID]
+			if !ok {
+				i = len(result.Breakdown)
+				byRate[rate.ID] = i
+				result.Breakdown = append(result.Breakdown, taxBreakdown{
+					TaxRateID: rate.ID,
+					Code:      rate.Code,
+					Name:      rate.Name,
+					Rate:      rate.Rate,
+					Inclusive: rate.Inclusive,
+					taxable:   new(big.Rat),
+					tax:       new(big.Rat),
+				})
+			}
+			b := &result.Breakdown[i]
+			b.TaxableAmount += net
+			b.TaxAmount += amount
+			b.taxable.Add(b.taxable, exactNet)
+			b.tax.Add(b.tax, exact)
+		}
+
+		if !perLine {
+			lineTaxTotal = models.RoundRat(exactLineTax)
+		}
+		result.Lines = append(result.Lines, lineTax{Net: net, Tax: lineTaxTotal})
+		exactNets = append(exactNets, exactNet)
+		exactTaxes = append(exactTaxes, exactLineTax)
+		gross += line.Amount
+	}
+
+	for i := range result.Breakdown {
+		b := &result.Breakdown[i]
+		if !perLine {
+			b.TaxableAmount = models.RoundRat(b.taxable)
+			b.TaxAmount = models.RoundRat(b.tax)
+		}
+		if b.Inclusive {
+			inclusiveTax += b.TaxAmount
+		} else {
+			exclusiveTax += b.TaxAmount
+		}
+	}
+
+	result.Total = gross + exclusiveTax
+	result.Tax = inclusiveTax + exclusiveTax
+	result.Subtotal = result.Total - result.Tax
+
+	if !perLine {
+		// Each rate's total was rounded once, so the rounded lines can be a
+		// cent or two off the totals; spread the difference over the lines
+		nets := make([]models.Money, len(result.Lines))
+		taxes := make([]models.Money, len(result.Lines))
+		for i, line := range result.Lines {
+			nets[i] = line.Net
+			taxes[i] = line.Tax
+		}
+		spreadRounding(nets, exactNets, result.Subtotal)
+		spreadRounding(taxes, exactTaxes, result.Tax)
+		for i := range result.Lines {
+			result.Lines[i] = lineTax{Net: nets[i], Tax: taxes[i]}
+		}
+	}
+	return result
+}
+
+// spreadRounding moves rounded amounts a cent at a time until they add up to
+// total, starting with the amounts rounding moved furthest from their exact
+// value. Amounts that are exactly zero are left alone when others can take it.
+func spreadRounding(rounded []models.Money, exact []*big.Rat, total models.Money) {
+	diff := total
+	for _, amount := range rounded {
+		diff -= amount
+	}
+	if diff == 0 || len(rounded) == 0 {
+		return
+	}
+	step := models.Money(1)
+	if diff < 0 {
+		step = -1
+	}
+
+	candidates := []int{}
+	for i := range rounded {
+		if exact[i].Sign() != 0 {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range rounded {
+			candidates = append(candidates, i)
+		}
+	}
+
+	// How far rounding moved each amount against the direction of the step
+	shortfall := make([]*big.Rat, len(rounded))
+	for _, i := range candidates {
+		shortfall[i] = new(big.Rat).Sub(exact[i], rounded[i].Rat())
+		if step < 0 {
+			shortfall[i].Neg(shortfall[i])
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return shortfall[candidates[a]].Cmp(shortfall[candidates[b]]) > 0
+	})
+
+	for i := 0; diff != 0; i = (i + 1) % len(candidates) {
+		rounded[candidates[i]] += step
+		diff -= step
+	}
+}
+
+// taxClasses resolves which tax rates apply to an item
+type taxClasses struct {
+	byID      map[uint]models.TaxClass
+	byType    map[string]models.TaxClass
+	byDefault *models.TaxClass
+}
+
+func loadTaxClasses(tx *gorm.DB) (*taxClasses, error) {
+	var classes []models.TaxClass
+	if err := tx.Preload("TaxRates", "is_active = ?", true).Order("id").Find(&classes).Error; err != nil {
+		return nil, err
+	}
+
+	lookup := &taxClasses{byID: map[uint]models.TaxClass{}, byType: map[string]models.TaxClass{}}
+	for i, class := range classes {
+		lookup.byID[class.ID] = class
+		if _, ok := lookup.byType[class.ItemType]; class.ItemType != "" && !ok {
+			lookup.byType[class.ItemType] = class
+		}
+		if class.IsDefault && lookup.byDefault == nil {
+			lookup.byDefault = &classes[i]
+		}
+	}
+	return lookup, nil
+}
+
+// ratesFor returns the rates of the item's own class, else of its item type's
+// class, else of the default class; items with none of these are not taxed
+func (c *taxClasses) ratesFor(itemType string, taxClassID *uint) []models.TaxRate {
+	if taxClassID != nil {
+		if class, ok := c.byID[*taxClassID]; ok {
+			return class.TaxRates
+		}
+	}
+	if class, ok := c.byType[itemType]; ok {
+		return class.TaxRates
+	}
+	if c.byDefault != nil {
+		return c.byDefault.TaxRates
+	}
+	return nil
+}
+
+// calculateOrderTax runs the tax engine over order lines, filling in each
+// line's NetAmount and TaxAmount. It is the single tax computation shared by
+// CreateOrder, order amendments and CreateInvoice.
+func calculateOrderTax(tx *gorm.DB, orderItems []models.OrderItem) (taxResult, error) {
+	classes, err := loadTaxClasses(tx)
+	if err != nil {
+		return taxResult{}, err
+	}
+
+	itemIDs := make([]uint, 0, len(orderItems))
+	for _, orderItem := range orderItems {
+		itemIDs = append(itemIDs, orderItem.ItemID)
+	}
+	// Unscoped: a line keeps its tax class even if the item was deleted since
+	var items []models.Item
+	if err := tx.Unscoped().Where("id IN ?", itemIDs).Find(&items).Error; err != nil {
+		return taxResult{}, err
+	}
+	itemsByID := map[uint]models.Item{}
+	for _, item := range items {
+		itemsByID[item.ID] = item
+	}
+
+	lines := make([]taxableLine, 0, len(orderItems))
+	for _, orderItem := range orderItems {
+		item := itemsByID[orderItem.ItemID]
+		lines = append(lines, taxableLine{
+			Amount: orderItem.TotalPrice,
+			Rates:  classes.ratesFor(item.Type, item.TaxClassID),
+		})
+	}
+
+	result := calculateTax(lines)
+	for i := range orderItems {
+		orderItems[i].NetAmount = result.Lines[i].Net
+		orderItems[i].TaxAmount = result.Lines[i].Tax
+	}
+	return result, nil
+}
+
+// applyOrderTax recalculates tax for an order whose OrderItems are loaded and
+// saves the line amounts and order totals
+func applyOrderTax(tx *gorm.DB, order *models.Order) (taxResult, error) {
+	result, err := calculateOrderTax(tx, order.OrderItems)
+	if err != nil {
+		return result, err
+	}
+
+	for _, orderItem := range order.OrderItems {
+		if err := tx.Model(&models.OrderItem{}).Where("id = ?", orderItem.ID).Updates(map[string]interface{}{
+			"net_amount": orderItem.NetAmount,
+			"tax_amount": orderItem.TaxAmount,
+		}).Error; err != nil {
+			return result, err
+		}
+	}
+
+	order.Subtotal = result.Subtotal
+	order.Tax = result.Tax
+	order.TotalAmount = result.Total
+	err = tx.Model(&models.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+		"subtotal":     order.Subtotal,
+		"tax":          order.Tax,
+		"total_amount": order.TotalAmount,
+	}).Error
+	return result, err
+}
+
+// invoiceTaxes converts a tax breakdown into rows stored on the invoice
+func invoiceTaxes(breakdown []taxBreakdown) []models.InvoiceTax {
+	taxes := make([]models.InvoiceTax, 0, len(breakdown))
+	for _, b := range breakdown {
+		taxes = append(taxes, models.InvoiceTax{
+			TaxRateID:     b.TaxRateID,
+			Code:          b.Code,
+			Name:          b.Name,
+			Rate:          b.Rate,
+			Inclusive:     b.Inclusive,
+			TaxableAmount: b.TaxableAmount,
+			TaxAmount:     b.TaxAmount,
+		})
+	}
+	return taxes
+}
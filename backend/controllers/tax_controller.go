@@ -0,0 +1,417 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"main/models"
+	"main/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Request structures. Fields are pointers so updates only touch what was sent.
+type TaxRateRequest struct {
+	Code      *string  `json:"code"`
+	Name      *string  `json:"name"`
+	Rate      *float64 `json:"rate"` // Percentage, e.g. 18 for 18%
+	Inclusive *bool    `json:"inclusive"`
+	IsActive  *bool    `json:"is_active"`
+}
+
+type TaxClassRequest struct {
+	Code       *string `json:"code"`
+	Name       *string `json:"name"`
+	ItemType   *string `json:"item_type"`
+	IsDefault  *bool   `json:"is_default"`
+	TaxRateIDs []uint  `json:"tax_rate_ids"` // Replaces the class's rates when present
+}
+
+func GetTaxRates(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/tax-rates called")
+
+	var rates []models.TaxRate
+	if err := db.Order("code").Find(&rates).Error; err != nil {
+		log.Printf("Error fetching tax rates: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve tax rates",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Tax rates retrieved successfully",
+		Data:    rates,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func CreateTaxRate(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /api/tax-rates called")
+
+	var req TaxRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if msg := req.validate(true); msg != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	rate := models.TaxRate{
+		Code:     strings.ToUpper(strings.TrimSpace(*req.Code)),
+		Name:     strings.TrimSpace(*req.Name),
+		Rate:     *req.Rate,
+		IsActive: true,
+	}
+	if req.Inclusive != nil {
+		rate.Inclusive = *req.Inclusive
+	}
+
+	if err := db.Create(&rate).Error; err != nil {
+		log.Printf("Error creating tax rate: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to create tax rate",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Tax rate created successfully",
+		Data:    rate,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func UpdateTaxRate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("PUT /api/tax-rates/%s called", id)
+
+	rateID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid tax rate ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req TaxRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if msg := req.validate(false); msg != "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var rate models.TaxRate
+	if err := db.First(&rate, uint(rateID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Tax rate not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching tax rate: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to update tax rate",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	// Invoices keep their own copy of the rate, so editing a rate never changes issued invoices
+	if err := db.Model(&rate).Updates(req.updates()).Error; err != nil {
+		log.Printf("Error updating tax rate: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to update tax rate",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	if err := db.First(&rate, uint(rateID)).Error; err != nil {
+		log.Printf("Error reloading tax rate: %v", err)
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Tax rate updated successfully",
+		Data:    rate,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func GetTaxClasses(w http.ResponseWriter, r *http.Request) {
+	log.Println("GET /api/tax-classes called")
+
+	var classes []models.TaxClass
+	if err := db.Preload("TaxRates").Order("code").Find(&classes).Error; err != nil {
+		log.Printf("Error fetching tax classes: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve tax classes",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Tax classes retrieved successfully",
+		Data:    classes,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func CreateTaxClass(w http.ResponseWriter, r *http.Request) {
+	log.Println("POST /api/tax-classes called")
+
+	var req TaxClassRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if req.Code == nil || strings.TrimSpace(*req.Code) == "" || req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Code and name are required",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	class := models.TaxClass{
+		Code: strings.ToUpper(strings.TrimSpace(*req.Code)),
+		Name: strings.TrimSpace(*req.Name),
+	}
+	if req.ItemType != nil {
+		class.ItemType = strings.ToLower(*req.ItemType)
+	}
+	if req.IsDefault != nil {
+		class.IsDefault = *req.IsDefault
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		rates, err := findTaxRates(tx, req.TaxRateIDs)
+		if err != nil {
+			return err
+		}
+		class.TaxRates = rates
+		return tx.Omit("TaxRates.*").Create(&class).Error
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to create tax class")
+		return
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Tax class created successfully",
+		Data:    class,
+	}
+	utils.SendJSONResponse(w, http.StatusCreated, response)
+}
+
+func UpdateTaxClass(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	log.Printf("PUT /api/tax-classes/%s called", id)
+
+	classID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid tax class ID",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var req TaxClassRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	var class models.TaxClass
+	if err := db.First(&class, uint(classID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response := utils.APIResponse{
+				Success: false,
+				Message: "Tax class not found",
+				Data:    nil,
+			}
+			utils.SendJSONResponse(w, http.StatusNotFound, response)
+			return
+		}
+
+		log.Printf("Error fetching tax class: %v", err)
+		response := utils.APIResponse{
+			Success: false,
+			Message: "Failed to update tax class",
+			Data:    nil,
+		}
+		utils.SendJSONResponse(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Code != nil && strings.TrimSpace(*req.Code) != "" {
+		updates["code"] = strings.ToUpper(strings.TrimSpace(*req.Code))
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) != "" {
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.ItemType != nil {
+		updates["item_type"] = strings.ToLower(*req.ItemType)
+	}
+	if req.IsDefault != nil {
+		updates["is_default"] = *req.IsDefault
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&class).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.TaxRateIDs != nil {
+			rates, err := findTaxRates(tx, req.TaxRateIDs)
+			if err != nil {
+				return err
+			}
+			return tx.Model(&class).Association("TaxRates").Replace(rates)
+		}
+		return nil
+	})
+	if err != nil {
+		sendRequestError(w, err, "Failed to update tax class")
+		return
+	}
+
+	if err := db.Preload("TaxRates").First(&class, uint(classID)).Error; err != nil {
+		log.Printf("Error reloading tax class: %v", err)
+	}
+
+	response := utils.APIResponse{
+		Success: true,
+		Message: "Tax class updated successfully",
+		Data:    class,
+	}
+	utils.SendJSONResponse(w, http.StatusOK, response)
+}
+
+func (req TaxRateRequest) validate(create bool) string {
+	if create && (req.Code == nil || req.Name == nil || req.Rate == nil) {
+		return "Code, name and rate are required"
+	}
+	if req.Code != nil && strings.TrimSpace(*req.Code) == "" {
+		return "Code cannot be empty"
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		return "Name cannot be empty"
+	}
+	if req.Rate != nil && (*req.Rate < 0 || *req.Rate > 100) {
+		return "Rate must be a percentage between 0 and 100"
+	}
+	return ""
+}
+
+func (req TaxRateRequest) updates() map[string]interface{} {
+	updates := map[string]interface{}{}
+	if req.Code != nil {
+		updates["code"] = strings.ToUpper(strings.TrimSpace(*req.Code))
+	}
+	if req.Name != nil {
+		updates["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Rate != nil {
+		updates["rate"] = *req.Rate
+	}
+	if req.Inclusive != nil {
+		updates["inclusive"] = *req.Inclusive
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	return updates
+}
+
+// Helper function to load tax rates by ID, failing on unknown IDs
+func findTaxRates(tx *gorm.DB, ids []uint) ([]models.TaxRate, error) {
+	if len(ids) == 0 {
+		return []models.TaxRate{}, nil
+	}
+
+	var rates []models.TaxRate
+	if err := tx.Where("id IN ?", ids).Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	if len(rates) != len(ids) {
+		return nil, badRequest("One or more tax rates in tax_rate_ids do not exist")
+	}
+	return rates, nil
+}
@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"main/models"
+
+	"gorm.io/gorm"
+)
+
+// Response structures
+type TaxReport struct {
+	Period         reportPeriod      `json:"period"`
+	Invoices       TaxReportTotals   `json:"invoices"` // Invoices issued in the period, voided ones excluded
+	VoidedInvoices int64             `json:"voided_invoices"`
+	TaxableSales   models.Money      `json:"taxable_sales"` // Net amount of lines that carry tax
+	ExemptSales    models.Money      `json:"exempt_sales"`  // Net amount of lines without tax
+	Rates          []TaxReportRate   `json:"rates"`
+	CreditNotes    TaxReportTotals   `json:"credit_notes"` // Credit notes dated in the period, as positive amounts
+	TaxCollected   models.Money      `json:"tax_collected"`
+	TaxRefunded    models.Money      `json:"tax_refunded"`
+	NetTax         models.Money      `json:"net_tax"` // Tax collected less tax refunded
+	Reconciliation TaxReconciliation `json:"reconciliation"`
+}
+
+type TaxReportTotals struct {
+	Count int64        `json:"count"`
+	Net   models.Money `json:"net"`
+	Tax   models.Money `json:"tax"`
+	Gross models.Money `json:"gross"`
+}
+
+type TaxReportRate struct {
+	Code          string       `json:"code"`
+	Name          string       `json:"name"`
+	Rate          float64      `json:"rate"`
+	Inclusive     bool         `json:"inclusive"`
+	TaxableAmount models.Money `json:"taxable_amount"`
+	TaxCollected  models.Money `json:"tax_collected"`
+	TaxRefunded   models.Money `json:"tax_refunded"`
+	NetTax        models.Money `json:"net_tax"`
+}
+
+// TaxReconciliation compares the report's breakdowns with the invoice totals
+// they should add up to. A difference points at invoices whose stored totals
+// disagree with their lines or tax rows.
+type TaxReconciliation struct {
+	InvoiceNet    models.Money `json:"invoice_net"`    // Sum of invoice subtotals
+	LinesNet      models.Money `json:"lines_net"`      // Taxable plus exempt sales
+	NetDifference models.Money `json:"net_difference"` // InvoiceNet - LinesNet
+	InvoiceTax    models.Money `json:"invoice_tax"`    // Sum of invoice tax amounts
+	RatesTax      models.Money `json:"rates_tax"`      // Sum of tax collected per rate
+	TaxDifference models.Money `json:"tax_difference"` // InvoiceTax - RatesTax
+	Balanced      bool         `json:"balanced"`
+}
+
+var filingPeriodPattern = regexp.MustCompile(`^(\d{4})-(?:(\d{2})|Q([1-4]))$`)
+
+// parseFilingPeriod reads the period query parameter, a month (2025-03) or a
+// quarter (2025-Q1), in the tz timezone. Without it the from and to
+// parameters are used as for other reports.
+func parseFilingPeriod(r *http.Request) (reportPeriod, error) {
+	value := r.URL.Query().Get("period")
+	if value == "" {
+		return parseReportPeriod(r)
+	}
+
+	location, err := shopLocation(r.URL.Query().Get("tz"))
+	if err != nil {
+		return reportPeriod{}, err
+	}
+
+	match := filingPeriodPattern.FindStringSubmatch(value)
+	if match == nil {
+		return reportPeriod{}, badRequest("Invalid period %q, expected a month (2025-03) or a quarter (2025-Q1)", value)
+	}
+	year, _ := strconv.Atoi(match[1])
+	month, months := 1, 1
+	if match[2] != "" {
+		month, _ = strconv.Atoi(match[2])
+		if month < 1 || month > 12 {
+			return reportPeriod{}, badRequest("Invalid month in period %q", value)
+		}
+	} else {
+		quarter, _ := strconv.Atoi(match[3])
+		month, months = (quarter-1)*3+1, 3
+	}
+
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, location)
+	return newReportPeriod(start, start.AddDate(0, months, 0), location), nil
+}
+
+// buildTaxReport summarises the tax on invoices issued in the period and on
+// credit notes dated in it
+func buildTaxReport(tx *gorm.DB, period reportPeriod) (TaxReport, error) {
+	report := TaxReport{Period: period, Rates: []TaxReportRate{}}
+
+	invoices := tx.Model(&models.Invoice{}).
+		Where("invoice_date >= ? AND invoice_date < ?", period.Start, period.End).
+		Session(&gorm.Session{})
+
+	if err := invoices.Where("payment_status = ?", "cancelled").Count(&report.VoidedInvoices).Error; err != nil {
+		return report, err
+	}
+	if err := invoices.
+		Select(`COUNT(*) AS count,
+			COALESCE(SUM(subtotal_amount), 0) AS net,
+			COALESCE(SUM(tax_amount), 0) AS tax,
+			COALESCE(SUM(total_amount), 0) AS gross`).
+		Where("payment_status <> ?", "cancelled").
+		Scan(&report.Invoices).Error; err != nil {
+		return report, err
+	}
+
+	var sales struct {
+		Taxable models.Money
+		Exempt  models.Money
+	}
+	if err := tx.Table("order_items").
+		Select(`COALESCE(SUM(order_items.net_amount) FILTER (WHERE order_items.tax_amount <> 0), 0) AS taxable,
+			COALESCE(SUM(order_items.net_amount) FILTER (WHERE order_items.tax_amount = 0), 0) AS exempt`).
+		Joins("JOIN invoices ON invoices.order_id = order_items.order_id AND invoices.deleted_at IS NULL").
+		Where("order_items.deleted_at IS NULL AND invoices.payment_status <> ?", "cancelled").
+		Where("invoices.invoice_date >= ? AND invoices.invoice_date < ?", period.Start, period.End).
+		Scan(&sales).Error; err != nil {
+		return report, err
+	}
+	report.TaxableSales = sales.Taxable
+	report.ExemptSales = sales.Exempt
+
+	var collected []TaxReportRate
+	if err := tx.Table("invoice_taxes").
+		Select(`invoice_taxes.code, invoice_taxes.name, invoice_taxes.rate, invoice_taxes.inclusive,
+			SUM(invoice_taxes.taxable_amount) AS taxable_amount,
+			SUM(invoice_taxes.tax_amount) AS tax_collected`).
+		Joins("JOIN invoices ON invoices.id = invoice_taxes.invoice_id AND invoices.deleted_at IS NULL").
+		Where("invoices.payment_status <> ?", "cancelled").
+		Where("invoices.invoice_date >= ? AND invoices.invoice_date < ?", period.Start, period.End).
+		Group("invoice_taxes.code, invoice_taxes.name, invoice_taxes.rate, invoice_taxes.inclusive").
+		Scan(&collected).Error; err != nil {
+		return report, err
+	}
+
+	// Credit note amounts are negative, the report shows what was given back
+	var refunded []TaxReportRate
+	if err := tx.Table("credit_note_taxes").
+		Select(`credit_note_taxes.code, credit_note_taxes.name, credit_note_taxes.rate, credit_note_taxes.inclusive,
+			-SUM(credit_note_taxes.tax_amount) AS tax_refunded`).
+		Joins("JOIN credit_notes ON credit_notes.id = credit_note_taxes.credit_note_id AND credit_notes.deleted_at IS NULL").
+		Where("credit_notes.credit_note_date >= ? AND credit_notes.credit_note_date < ?", period.Start, period.End).
+		Group("credit_note_taxes.code, credit_note_taxes.name, credit_note_taxes.rate, credit_note_taxes.inclusive").
+		Scan(&refunded).Error; err != nil {
+		return report, err
+	}
+	report.Rates = mergeTaxReportRates(collected, refunded)
+
+	if err := tx.Model(&models.CreditNote{}).
+		Select(`COUNT(*) AS count,
+			COALESCE(-SUM(subtotal_amount), 0) AS net,
+			COALESCE(-SUM(tax_amount), 0) AS tax,
+			COALESCE(-SUM(total_amount), 0) AS gross`).
+		Where("credit_note_date >= ? AND credit_note_date < ?", period.Start, period.End).
+		Scan(&report.CreditNotes).Error; err != nil {
+		return report, err
+	}
+
+	for _, rate := range report.Rates {
+		report.TaxCollected += rate.TaxCollected
+		report.TaxRefunded += rate.TaxRefunded
+	}
+	report.NetTax = report.TaxCollected - report.TaxRefunded
+
+	reconciliation := TaxReconciliation{
+		InvoiceNet: report.Invoices.Net,
+		LinesNet:   report.TaxableSales + report.ExemptSales,
+		InvoiceTax: report.Invoices.Tax,
+		RatesTax:   report.TaxCollected,
+	}
+	reconciliation.NetDifference = reconciliation.InvoiceNet - reconciliation.LinesNet
+	reconciliation.TaxDifference = reconciliation.InvoiceTax - reconciliation.RatesTax
+	reconciliation.Balanced = reconciliation.NetDifference == 0 && reconciliation.TaxDifference == 0
+	report.Reconciliation = reconciliation
+
+	return report, nil
+}
+
+// mergeTaxReportRates joins the collected and refunded tax of each rate
+func mergeTaxReportRates(collected, refunded []TaxReportRate) []TaxReportRate {
+	key := func(rate TaxReportRate) string {
+		return fmt.Sprintf("%s|%s|%g|%t", rate.Code, rate.Name, rate.Rate, rate.Inclusive)
+	}
+
+	rates := []TaxReportRate{}
+	index := map[string]int{}
+	for _, rate := range collected {
+		index[key(rate)] = len(rates)
+		rates = append(rates, rate)
+	}
+	for _, rate := range refunded {
+		i, ok := index[key(rate)]
+		if !ok {
+			i = len(rates)
+			index[key(rate)] = i
+			rates = append(rates, TaxReportRate{Code: rate.Code, Name: rate.Name, Rate: rate.Rate, Inclusive: rate.Inclusive})
+		}
+		rates[i].TaxRefunded += rate.TaxRefunded
+	}
+	for i := range rates {
+		rates[i].NetTax = rates[i].TaxCollected - rates[i].TaxRefunded
+	}
+	return rates
+}
+
+// csvRows renders one row per rate, the exempt sales and the totals
+func (report TaxReport) csvRows() [][]string {
+	rows := make([][]string, 0, len(report.Rates)+2)
+	for _, rate := range report.Rates {
+		rows = append(rows, []string{
+			rate.Code,
+			rate.Name,
+			strconv.FormatFloat(rate.Rate, 'f', -1, 64),
+			strconv.FormatBool(rate.Inclusive),
+			rate.TaxableAmount.String(),
+			rate.TaxCollected.String(),
+			rate.TaxRefunded.String(),
+			rate.NetTax.String(),
+		})
+	}
+	rows = append(rows,
+		[]string{"EXEMPT", "Exempt sales", "0", "false", report.ExemptSales.String(), "0.00", "0.00", "0.00"},
+		[]string{"TOTAL", "Total", "", "", report.TaxableSales.String(), report.TaxCollected.String(), report.TaxRefunded.String(), report.NetTax.String()},
+	)
+	return rows
+}
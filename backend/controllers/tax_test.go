@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"math/big"
+	"testing"
+
+	"main/models"
+)
+
+var (
+	vat18       = models.TaxRate{ID: 1, Code: "VAT", Rate: 18, Inclusive: true}
+	vat15       = models.TaxRate{ID: 2, Code: "VAT15", Rate: 15}
+	service10   = models.TaxRate{ID: 3, Code: "SVC", Rate: 10}
+	levy2_5     = models.TaxRate{ID: 4, Code: "LEVY", Rate: 2.5, Inclusive: true}
+	vatIncluded = models.TaxRate{ID: 5, Code: "VATI", Rate: 15, Inclusive: true}
+)
+
+func money(t *testing.T, value string) models.Money {
+	t.Helper()
+	amount, err := models.ParseMoney(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amount
+}
+
+// checkLinesAddUp fails when the line amounts disagree with the totals
+func checkLinesAddUp(t *testing.T, result taxResult) {
+	t.Helper()
+	var net, tax models.Money
+	for _, line := range result.Lines {
+		net += line.Net
+		tax += line.Tax
+	}
+	if net != result.Subtotal || tax != result.Tax {
+		t.Errorf("lines add up to net %s, tax %s; totals are %s, %s", net, tax, result.Subtotal, result.Tax)
+	}
+	var breakdown models.Money
+	for _, b := range result.Breakdown {
+		breakdown += b.TaxAmount
+	}
+	if breakdown != result.Tax {
+		t.Errorf("breakdown adds up to %s, tax is %s", breakdown, result.Tax)
+	}
+	if result.Subtotal+result.Tax != result.Total {
+		t.Errorf("subtotal %s + tax %s != total %s", result.Subtotal, result.Tax, result.Total)
+	}
+}
+
+func TestCalculateTax(t *testing.T) {
+	tests := []struct {
+		name     string
+		rounding string
+		amounts  []string
+		rates    []models.TaxRate
+		subtotal string
+		tax      string
+		total    string
+	}{
+		{"exclusive", "line", []string{"100.00"}, []models.TaxRate{vat15}, "100.00", "15.00", "115.00"},
+		{"inclusive", "line", []string{"118.00"}, []models.TaxRate{vat18}, "100.00", "18.00", "118.00"},
+		{"inclusive and exclusive", "line", []string{"115.00"}, []models.TaxRate{vatIncluded, service10}, "100.00", "25.00", "125.00"},
+		{"two inclusive rates", "line", []string{"120.50"}, []models.TaxRate{vat18, levy2_5}, "100.00", "20.50", "120.50"},
+		{"untaxed", "line", []string{"50.00"}, nil, "50.00", "0.00", "50.00"},
+		// 1.5 cents per line: rounded per line it is 2 cents each
+		{"line rounding", "line", []string{"0.10", "0.10", "0.10"}, []models.TaxRate{vat15}, "0.30", "0.06", "0.36"},
+		// 4.5 cents in total, rounded once
+		{"invoice rounding", "invoice", []string{"0.10", "0.10", "0.10"}, []models.TaxRate{vat15}, "0.30", "0.05", "0.35"},
+		{"invoice rounding inclusive", "invoice", []string{"0.99", "0.99", "0.99"}, []models.TaxRate{vat18}, "2.52", "0.45", "2.97"},
+		{"invoice rounding mixed", "invoice", []string{"10.01", "3.33", "7.77", "0.01"}, []models.TaxRate{vatIncluded, service10}, "18.37", "4.59", "22.96"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TAX_ROUNDING", tt.rounding)
+			lines := []taxableLine{}
+			for _, amount := range tt.amounts {
+				lines = append(lines, taxableLine{Amount: money(t, amount), Rates: tt.rates})
+			}
+
+			result := calculateTax(lines)
+			if result.Subtotal != money(t, tt.subtotal) || result.Tax != money(t, tt.tax) || result.Total != money(t, tt.total) {
+				t.Errorf("got subtotal %s, tax %s, total %s; want %s, %s, %s",
+					result.Subtotal, result.Tax, result.Total, tt.subtotal, tt.tax, tt.total)
+			}
+			checkLinesAddUp(t, result)
+		})
+	}
+}
+
+// Credit notes refund line amounts, so in invoice mode the lines must carry
+// the invoice-level rounding
+func TestCalculateTaxInvoiceRoundingSpreadsOverLines(t *testing.T) {
+	t.Setenv("TAX_ROUNDING", "invoice")
+	lines := []taxableLine{
+		{Amount: money(t, "0.10"), Rates: []models.TaxRate{vat15}},
+		{Amount: money(t, "0.10"), Rates: []models.TaxRate{vat15}},
+		{Amount: money(t, "0.10"), Rates: []models.TaxRate{vat15}},
+		{Amount: money(t, "5.00")}, // Untaxed lines take none of the tax
+	}
+
+	result := calculateTax(lines)
+	checkLinesAddUp(t, result)
+	if result.Lines[3].Tax != 0 || result.Lines[3].Net != money(t, "5.00") {
+		t.Errorf("untaxed line got net %s, tax %s", result.Lines[3].Net, result.Lines[3].Tax)
+	}
+	for i, line := range result.Lines[:3] {
+		if line.Tax < 1 || line.Tax > 2 {
+			t.Errorf("line %d tax %s is more than a cent off its exact 1.5 cents", i, line.Tax)
+		}
+	}
+}
+
+func TestSpreadRounding(t *testing.T) {
+	tests := []struct {
+		name    string
+		rounded []models.Money
+		exact   []string // In cents
+		total   models.Money
+		want    []models.Money
+	}{
+		{"already adds up", []models.Money{2, 3}, []string{"2", "3"}, 5, []models.Money{2, 3}},
+		{"take from the most rounded up", []models.Money{2, 2, 2}, []string{"1.5", "1.6", "1.7"}, 5, []models.Money{1, 2, 2}},
+		{"give to the most rounded down", []models.Money{1, 1}, []string{"1.2", "1.4"}, 3, []models.Money{1, 2}},
+		{"zero lines left alone", []models.Money{0, 4}, []string{"0", "4.4"}, 5, []models.Money{0, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exact := make([]*big.Rat, len(tt.exact))
+			for i, value := range tt.exact {
+				exact[i], _ = new(big.Rat).SetString(value)
+			}
+			spreadRounding(tt.rounded, exact, tt.total)
+			for i := range tt.want {
+				if tt.rounded[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", tt.rounded, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTaxClassesRatesFor(t *testing.T) {
+	own := models.TaxClass{ID: 1, Code: "ZERO", TaxRates: []models.TaxRate{}}
+	beverage := models.TaxClass{ID: 2, Code: "BEV", ItemType: "beverage", TaxRates: []models.TaxRate{vat18}}
+	standard := models.TaxClass{ID: 3, Code: "STD", IsDefault: true, TaxRates: []models.TaxRate{vat15}}
+	classes := &taxClasses{
+		byID:      map[uint]models.TaxClass{1: own, 2: beverage, 3: standard},
+		byType:    map[string]models.TaxClass{"beverage": beverage},
+		byDefault: &standard,
+	}
+	ownID, missingID := uint(1), uint(99)
+
+	tests := []struct {
+		name       string
+		itemType   string
+		taxClassID *uint
+		want       string // Code of the first rate, "" for none
+	}{
+		{"item's own class wins", "beverage", &ownID, ""},
+		{"item type class", "beverage", nil, "VAT"},
+		{"default class", "pizza", nil, "VAT15"},
+		{"unknown own class falls back", "pizza", &missingID, "VAT15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates := classes.ratesFor(tt.itemType, tt.taxClassID)
+			got := ""
+			if len(rates) > 0 {
+				got = rates[0].Code
+			}
+			if got != tt.want {
+				t.Errorf("ratesFor(%q) = %q, want %q", tt.itemType, got, tt.want)
+			}
+		})
+	}
+
+	if rates := (&taxClasses{byID: map[uint]models.TaxClass{}, byType: map[string]models.TaxClass{}}).ratesFor("pizza", nil); rates != nil {
+		t.Errorf("without a default class items are untaxed, got %v", rates)
+	}
+}
@@ -22,15 +22,41 @@ func main() {
 
 	controllers.SetDB(DB) // Set the database connection in controllers package
 
+	if err := migrateMoneyColumns(); err != nil {
+		panic("Failed to migrate money columns: " + err.Error())
+	}
+
+	if err := repairVariantLinks(); err != nil {
+		panic("Failed to repair item variant links: " + err.Error())
+	}
+
 	err := DB.AutoMigrate(
 		&models.Customer{},
 		&models.Invoice{},
 		&models.Item{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderItemTopping{},
 		&models.Pizza{},
 		&models.Topping{},
-		&models.Beverage{}) // GORM creates the table if not exists
+		&models.Beverage{},
+		&models.PrintJob{},
+		&models.DocumentSequence{},
+		&models.TaxRate{},
+		&models.TaxClass{},
+		&models.InvoiceTax{},
+		&models.OrderStatusHistory{},
+		&models.CreditNote{},
+		&models.CreditNoteLine{},
+		&models.CreditNoteTax{},
+		&models.Payment{},
+		&models.CashSession{},
+		&models.CashMovement{},
+		&models.CustomerAddress{},
+		&models.DeliveryZone{},
+		&models.Driver{},
+		&models.DriverLocation{},
+		&models.DeliveryAssignment{}) // GORM creates the table if not exists
 	if err != nil {
 		panic("Failed to migrate Customer model: " + err.Error())
 	}
@@ -38,6 +64,40 @@ func main() {
 	DB.AutoMigrate(&models.Order{}, &models.OrderItem{}, &models.Item{})
 	DB.AutoMigrate(&models.Invoice{})
 
+	if err := normaliseItemTypes(); err != nil {
+		panic("Failed to normalise item types: " + err.Error())
+	}
+
+	if err := backfillOrderItemUnitPrices(); err != nil {
+		panic("Failed to backfill order line unit prices: " + err.Error())
+	}
+
+	if err := backfillInvoicePayments(); err != nil {
+		panic("Failed to backfill invoice payments: " + err.Error())
+	}
+
+	if err := backfillToppingItems(); err != nil {
+		panic("Failed to link toppings to topping items: " + err.Error())
+	}
+
+	if err := backfillOrderFulfilment(); err != nil {
+		panic("Failed to backfill order fulfilment types: " + err.Error())
+	}
+
+	if err := backfillCustomerPhones(); err != nil {
+		panic("Failed to normalise customer phone numbers: " + err.Error())
+	}
+
+	seedDefaultTaxes()
+
+	if err := seedDeliveryFeeItem(); err != nil {
+		panic("Failed to seed the delivery fee item: " + err.Error())
+	}
+
+	if err := backfillLegacyOrderTax(); err != nil {
+		panic("Failed to backfill the tax of legacy orders: " + err.Error())
+	}
+
 	router := routes.SetupRoutes()
 
 	// Apply CORS middleware
@@ -48,7 +108,7 @@ func main() {
 	log.Println("   Invoice Management: /api/invoices")
 	log.Println("   Customer Management: /api/customers")
 	log.Println("   Order Management: /api/orders")
-	// log.Println("   Dashboard: /api/dashboard/stats")
+	log.Println("   Dashboard: /api/dashboard/stats")
 
 	log.Fatal(http.ListenAndServe(":8080", handler))
 
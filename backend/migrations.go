@@ -0,0 +1,375 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"main/models"
+	"main/utils"
+
+	"gorm.io/gorm"
+)
+
+// moneyColumns lists every monetary column that used to be double precision
+var moneyColumns = map[string][]string{
+	"items":               {"unit_price"},
+	"pizzas":              {"price"},
+	"toppings":            {"price"},
+	"beverages":           {"price"},
+	"orders":              {"subtotal", "total_amount", "tax"},
+	"order_items":         {"unit_price", "toppings_price", "total_price", "net_amount", "tax_amount", "client_unit_price"},
+	"order_item_toppings": {"unit_price", "total_price"},
+	"invoices":            {"subtotal_amount", "tax_amount", "total_amount"},
+	"invoice_taxes":       {"taxable_amount", "tax_amount"},
+}
+
+// migrateMoneyColumns converts float money columns to NUMERIC(12,2), rounding
+// existing values to cents. It runs before AutoMigrate so the conversion is
+// explicit rather than left to GORM's column diffing.
+func migrateMoneyColumns() error {
+	for table, columns := range moneyColumns {
+		for _, column := range columns {
+			var dataType string
+			err := DB.Raw(
+				"SELECT data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?",
+				table, column,
+			).Scan(&dataType).Error
+			if err != nil {
+				return err
+			}
+			if dataType != "double precision" && dataType != "real" {
+				continue
+			}
+
+			sql := fmt.Sprintf(`ALTER TABLE %q ALTER COLUMN %q TYPE numeric(12,2) USING round(%q::numeric, 2)`, table, column, column)
+			if err := DB.Exec(sql).Error; err != nil {
+				return fmt.Errorf("converting %s.%s to numeric: %w", table, column, err)
+			}
+			log.Printf("Converted %s.%s from %s to numeric(12,2)", table, column, dataType)
+		}
+	}
+	return nil
+}
+
+// repairVariantLinks fixes rows that would break the foreign keys AutoMigrate
+// adds between items and their variants. Pizzas and beverages whose item was
+// removed get the item back as a deleted, inactive placeholder with the same
+// ID, as do toppings of extra toppings on order lines. Topping and order line
+// links to rows that no longer exist are cleared, the order line keeping its
+// description snapshot. Placeholders are priced 0, so restoring one requires
+// a new price.
+func repairVariantLinks() error {
+	migrator := DB.Migrator()
+	if !migrator.HasTable("items") {
+		return nil
+	}
+
+	restored := int64(0)
+	for table, itemType := range map[string]string{"pizzas": "pizza", "beverages": "beverage"} {
+		if !migrator.HasTable(table) {
+			continue
+		}
+		result := DB.Exec(fmt.Sprintf(`
+			INSERT INTO items (id, name, type, unit_price, is_active, created_at, updated_at, deleted_at)
+			SELECT v.item_id, MIN(v.name), ?, 0, false, NOW(), NOW(), NOW()
+			FROM %q v
+			WHERE NOT EXISTS (SELECT 1 FROM items i WHERE i.id = v.item_id)
+			GROUP BY v.item_id`, table), itemType)
+		if result.Error != nil {
+			return fmt.Errorf("restoring items of %s: %w", table, result.Error)
+		}
+		restored += result.RowsAffected
+	}
+	if restored > 0 {
+		log.Printf("Restored %d deleted items still referenced by pizzas or beverages", restored)
+		if err := DB.Exec(`SELECT setval(pg_get_serial_sequence('items', 'id'), (SELECT MAX(id) FROM items))`).Error; err != nil {
+			return err
+		}
+	}
+
+	// Extra toppings on order lines keep their topping the same way
+	if migrator.HasTable("order_item_toppings") && migrator.HasTable("toppings") {
+		result := DB.Exec(`
+			INSERT INTO toppings (id, topping_id, name, price, is_active, created_at, updated_at, deleted_at)
+			SELECT t.topping_id, 0, MIN(t.name), 0, false, NOW(), NOW(), NOW()
+			FROM order_item_toppings t
+			WHERE NOT EXISTS (SELECT 1 FROM toppings p WHERE p.id = t.topping_id)
+			GROUP BY t.topping_id`)
+		if result.Error != nil {
+			return fmt.Errorf("restoring toppings of order lines: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Restored %d deleted toppings still referenced by order lines", result.RowsAffected)
+			if err := DB.Exec(`SELECT setval(pg_get_serial_sequence('toppings', 'id'), (SELECT MAX(id) FROM toppings))`).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	links := []struct{ table, column, parent string }{
+		{"toppings", "item_id", "items"},
+		{"order_items", "pizza_id", "pizzas"},
+		{"order_items", "beverage_id", "beverages"},
+		{"order_items", "topping_id", "toppings"},
+	}
+	for _, link := range links {
+		if !migrator.HasColumn(link.table, link.column) {
+			continue
+		}
+		result := DB.Exec(fmt.Sprintf(`
+			UPDATE %q SET %q = NULL
+			WHERE %q IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %q p WHERE p.id = %q.%q)`,
+			link.table, link.column, link.column, link.parent, link.table, link.column))
+		if result.Error != nil {
+			return fmt.Errorf("clearing %s.%s: %w", link.table, link.column, result.Error)
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Cleared %d links from %s.%s to missing %s", result.RowsAffected, link.table, link.column, link.parent)
+		}
+	}
+	return nil
+}
+
+// normaliseItemTypes lower-cases item types, and the item types of tax classes,
+// saved before the API lower-cased them, so types compare with a plain ==
+func normaliseItemTypes() error {
+	for _, table := range []struct{ name, column string }{{"items", "type"}, {"tax_classes", "item_type"}} {
+		result := DB.Exec(fmt.Sprintf("UPDATE %s SET %s = LOWER(TRIM(%s)) WHERE %s <> LOWER(TRIM(%s))",
+			table.name, table.column, table.column, table.column, table.column))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Lower-cased the %s of %d %s", table.column, result.RowsAffected, table.name)
+		}
+	}
+	return nil
+}
+
+// backfillOrderItemUnitPrices gives order lines from before unit prices were
+// kept the unit price their total implies, so receipts do not print them as
+// "N x 0.00". Those lines had no extra toppings.
+func backfillOrderItemUnitPrices() error {
+	result := DB.Exec(`
+		UPDATE order_items SET unit_price = round(total_price / quantity, 2)
+		WHERE unit_price = 0 AND toppings_price = 0 AND total_price <> 0 AND quantity > 0`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Filled in the unit price of %d order lines", result.RowsAffected)
+	}
+	return nil
+}
+
+// backfillInvoicePayments gives invoices marked paid before payments were
+// recorded a single "legacy" payment for their total, so their status keeps
+// following from their payments
+func backfillInvoicePayments() error {
+	result := DB.Exec(`
+		INSERT INTO payments (invoice_id, method, amount, tendered, change, reference, paid_at, reversed, created_at, updated_at)
+		SELECT i.id, 'legacy', i.total_amount, 0, 0, 'Marked paid before payments were recorded',
+		       COALESCE(i.payment_date, i.updated_at), false, NOW(), NOW()
+		FROM invoices i
+		WHERE i.payment_status = 'paid' AND i.deleted_at IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.invoice_id = i.id)`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Recorded legacy payments for %d paid invoices", result.RowsAffected)
+	}
+
+	return DB.Exec(`
+		UPDATE invoices SET amount_paid = (
+			SELECT COALESCE(SUM(amount), 0) FROM payments
+			WHERE payments.invoice_id = invoices.id AND NOT payments.reversed AND payments.deleted_at IS NULL
+		)
+		WHERE payment_status = 'paid' AND amount_paid = 0`).Error
+}
+
+// seedDeliveryFeeItem creates the hidden item delivery fee lines point at, once,
+// before any order can need it. It is inserted inactive directly: is_active
+// defaults to true, and the fee must never show on the menu.
+func seedDeliveryFeeItem() error {
+	return DB.Exec(`
+		INSERT INTO items (name, type, unit_price, is_active, created_at, updated_at)
+		SELECT ?, 'delivery', 0, false, NOW(), NOW()
+		WHERE NOT EXISTS (SELECT 1 FROM items WHERE type = 'delivery' AND name = ?)`,
+		models.DeliveryFeeItemName, models.DeliveryFeeItemName).Error
+}
+
+// backfillCustomerPhones fills tel_e164 for customers created before phone
+// numbers were normalised. When several customers share a number only the
+// oldest gets it; the others show up in GET /api/customers/duplicates.
+func backfillCustomerPhones() error {
+	var customers []models.Customer
+	if err := DB.Where("tel_e164 IS NULL").Order("id").Find(&customers).Error; err != nil {
+		return err
+	}
+
+	skipped := 0
+	for _, customer := range customers {
+		tel, err := utils.NormalizePhone(customer.TelNo)
+		if err != nil {
+			skipped++
+			continue
+		}
+		var taken int64
+		if err := DB.Model(&models.Customer{}).Where("tel_e164 = ?", tel).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			skipped++
+			continue
+		}
+		if err := DB.Model(&customer).UpdateColumn("tel_e164", tel).Error; err != nil {
+			return err
+		}
+	}
+
+	if skipped > 0 {
+		log.Printf("%d customers have an invalid or duplicate phone number, see /api/customers/duplicates", skipped)
+	}
+	return nil
+}
+
+// backfillOrderFulfilment marks orders placed with a delivery address before
+// orders had a fulfilment type as delivery orders
+func backfillOrderFulfilment() error {
+	return DB.Exec(`
+		UPDATE orders SET fulfilment_type = 'delivery', courier = 'in_house'
+		WHERE delivery_address_id IS NOT NULL AND fulfilment_type = 'takeaway'`).Error
+}
+
+// backfillToppingItems links toppings created before toppings had a parent
+// item to a topping item of the same name, creating one at the topping's
+// price where none exists, so every topping sold as its own line is billed
+// against a topping item
+func backfillToppingItems() error {
+	result := DB.Exec(`
+		INSERT INTO items (name, type, unit_price, is_active, created_at, updated_at)
+		SELECT DISTINCT ON (lower(t.name)) t.name, 'topping', t.price, t.is_active AND t.deleted_at IS NULL, NOW(), NOW()
+		FROM toppings t
+		WHERE t.item_id IS NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM items i
+			WHERE lower(i.type) = 'topping' AND lower(i.name) = lower(t.name) AND i.deleted_at IS NULL)
+		ORDER BY lower(t.name), t.deleted_at IS NOT NULL, t.id`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Created %d topping items for toppings without a parent item", result.RowsAffected)
+	}
+
+	return DB.Exec(`
+		UPDATE toppings SET item_id = (
+			SELECT i.id FROM items i
+			WHERE lower(i.type) = 'topping' AND lower(i.name) = lower(toppings.name) AND i.deleted_at IS NULL
+			ORDER BY i.id LIMIT 1
+		)
+		WHERE item_id IS NULL`).Error
+}
+
+// legacyInvoiceTaxRate is the VAT CreateInvoice charged on top of the order
+// total before tax rates could be configured
+const legacyInvoiceTaxRate = 10
+
+// backfillLegacyOrderTax fills in the tax split of orders placed before order
+// lines carried one, so reports, credit notes and Z reports do not see them as
+// zero. The lines of an invoiced order share out the net and tax its invoice
+// billed in proportion to their totals; lines of an order without an invoice
+// are untaxed. The invoice gets a tax row for the legacy VAT so the tax report
+// reconciles, and the order its subtotal.
+func backfillLegacyOrderTax() error {
+	var orderIDs []uint
+	if err := DB.Model(&models.OrderItem{}).
+		Group("order_id").
+		Having("bool_and(net_amount = 0 AND tax_amount = 0) AND SUM(total_price) <> 0").
+		Order("order_id").
+		Pluck("order_id", &orderIDs).Error; err != nil {
+		return err
+	}
+	if len(orderIDs) == 0 {
+		return nil
+	}
+
+	var vatIDs []uint
+	if err := DB.Model(&models.TaxRate{}).Unscoped().Where("code = ?", "VAT").Order("id").Limit(1).Pluck("id", &vatIDs).Error; err != nil {
+		return err
+	}
+	vatID := uint(0)
+	if len(vatIDs) > 0 {
+		vatID = vatIDs[0]
+	}
+
+	for _, orderID := range orderIDs {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			var lines []models.OrderItem
+			if err := tx.Where("order_id = ?", orderID).Order("id").Find(&lines).Error; err != nil {
+				return err
+			}
+			var linesTotal models.Money
+			for _, line := range lines {
+				linesTotal += line.TotalPrice
+			}
+
+			net, tax := linesTotal, models.Money(0)
+			var invoice models.Invoice
+			err := tx.Where("order_id = ?", orderID).First(&invoice).Error
+			hasInvoice := err == nil
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if hasInvoice {
+				net, tax = invoice.SubtotalAmount, invoice.TaxAmount
+			}
+
+			// The last line takes the rounding remainder
+			netLeft, taxLeft := net, tax
+			for i, line := range lines {
+				lineNet, lineTax := netLeft, taxLeft
+				if i < len(lines)-1 {
+					lineNet = net.Prorate(int64(line.TotalPrice), int64(linesTotal))
+					lineTax = tax.Prorate(int64(line.TotalPrice), int64(linesTotal))
+				}
+				netLeft -= lineNet
+				taxLeft -= lineTax
+				if err := tx.Model(&models.OrderItem{}).Where("id = ?", line.ID).Updates(map[string]interface{}{
+					"net_amount": lineNet,
+					"tax_amount": lineTax,
+				}).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Exec(`UPDATE orders SET subtotal = total_amount - tax WHERE id = ? AND subtotal = 0`, orderID).Error; err != nil {
+				return err
+			}
+
+			if !hasInvoice || invoice.TaxAmount == 0 {
+				return nil
+			}
+			var rows int64
+			if err := tx.Model(&models.InvoiceTax{}).Where("invoice_id = ?", invoice.ID).Count(&rows).Error; err != nil || rows > 0 {
+				return err
+			}
+			return tx.Create(&models.InvoiceTax{
+				InvoiceID:     invoice.ID,
+				TaxRateID:     vatID,
+				Code:          "VAT",
+				Name:          "VAT",
+				Rate:          legacyInvoiceTaxRate,
+				TaxableAmount: invoice.SubtotalAmount,
+				TaxAmount:     invoice.TaxAmount,
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("order %d: %w", orderID, err)
+		}
+	}
+	log.Printf("Filled in the tax split of %d orders placed before lines carried tax", len(orderIDs))
+	return nil
+}
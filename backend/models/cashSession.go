@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CashSession is one cashier's shift on a cash drawer, from the opening float
+// to the counted cash at close
+type CashSession struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Cashier      string         `json:"cashier" gorm:"not null;index;uniqueIndex:idx_cash_sessions_open_cashier,where:status = 'open'"` // One open session per cashier
+	Drawer       string         `json:"drawer"`                                                                                         // Till or terminal name, optional
+	Status       string         `json:"status" gorm:"not null;default:'open';index"`                                                    // open, closed
+	OpenedAt     time.Time      `json:"opened_at" gorm:"not null"`
+	OpeningFloat Money          `json:"opening_float" gorm:"not null;default:0"`
+	ClosedAt     *time.Time     `json:"closed_at"`
+	ClosedBy     string         `json:"closed_by"`
+	ExpectedCash *Money         `json:"expected_cash"` // Set at close: float + cash taken - cash paid out
+	CountedCash  *Money         `json:"counted_cash"`
+	Difference   *Money         `json:"difference"` // CountedCash - ExpectedCash; negative when cash is short
+	Notes        string         `json:"notes"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Movements []CashMovement `json:"movements" gorm:"foreignKey:CashSessionID"`
+}
+
+// CashMovement is cash put into or taken out of a drawer outside of sales,
+// e.g. change brought from the safe or a supplier paid from the till
+type CashMovement struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	CashSessionID uint      `json:"cash_session_id" gorm:"not null;index"`
+	Type          string    `json:"type" gorm:"not null"`   // cash_in, cash_out
+	Amount        Money     `json:"amount" gorm:"not null"` // Always positive
+	Reason        string    `json:"reason" gorm:"not null"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreditNote is a negative invoice: it reverses all or some lines of a paid
+// invoice and records how the money went back to the customer. All amounts
+// on a credit note and its lines are negative.
+type CreditNote struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	InvoiceID        uint           `json:"invoice_id" gorm:"not null;index"`
+	CreditNoteNumber string         `json:"credit_note_number" gorm:"unique;not null"`
+	CreditNoteDate   time.Time      `json:"credit_note_date" gorm:"not null"`
+	SubtotalAmount   Money          `json:"subtotal_amount" gorm:"not null"`
+	TaxAmount        Money          `json:"tax_amount" gorm:"not null"`
+	TotalAmount      Money          `json:"total_amount" gorm:"not null"`
+	RefundMethod     string         `json:"refund_method" gorm:"not null"` // cash, card, bank_transfer, voucher
+	Reason           string         `json:"reason" gorm:"not null"`
+	CreatedBy        string         `json:"created_by"`
+	CashSessionID    *uint          `json:"cash_session_id" gorm:"index"`            // Session the refund was given in; cash refunds are paid from its drawer
+	CashRounding     Money          `json:"cash_rounding" gorm:"not null;default:0"` // Cash refunds are rounded like cash payments: the drawer pays out TotalAmount - CashRounding
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Lines []CreditNoteLine `json:"lines" gorm:"foreignKey:CreditNoteID"`
+	Taxes []CreditNoteTax  `json:"taxes" gorm:"foreignKey:CreditNoteID"`
+}
+
+// CreditNoteLine is the part of one order line that is refunded
+type CreditNoteLine struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	CreditNoteID uint      `json:"credit_note_id" gorm:"not null;index"`
+	OrderItemID  uint      `json:"order_item_id" gorm:"not null;index"`
+	Description  string    `json:"description"`
+	Quantity     int       `json:"quantity" gorm:"not null"` // Units refunded, positive
+	UnitPrice    Money     `json:"unit_price" gorm:"not null"`
+	NetAmount    Money     `json:"net_amount" gorm:"not null"`
+	TaxAmount    Money     `json:"tax_amount" gorm:"not null"`
+	TotalAmount  Money     `json:"total_amount" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CreditNoteTax is the tax reversed for one rate on a credit note
+type CreditNoteTax struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	CreditNoteID  uint      `json:"credit_note_id" gorm:"not null;index"`
+	TaxRateID     uint      `json:"tax_rate_id" gorm:"not null"`
+	Code          string    `json:"code" gorm:"not null"`
+	Name          string    `json:"name" gorm:"not null"`
+	Rate          float64   `json:"rate" gorm:"not null"`
+	Inclusive     bool      `json:"inclusive"`
+	TaxableAmount Money     `json:"taxable_amount" gorm:"not null"`
+	TaxAmount     Money     `json:"tax_amount" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -11,10 +11,14 @@ type Customer struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	Name      string         `json:"name" gorm:"not null"`
 	TelNo     string         `json:"tel_no" gorm:"not null"`
+	TelE164   *string        `json:"tel_e164" gorm:"uniqueIndex:idx_customers_tel_e164,where:deleted_at IS NULL"` // TelNo normalised, e.g. +94771234567
+	Email     string         `json:"email"`
+	Notes     string         `json:"notes"` // Preferences, allergies, ...
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	// Relationships
-	// Orders []Order `json:"orders" gorm:"foreignKey:CustomerID"`
+	Addresses []CustomerAddress `json:"addresses,omitempty" gorm:"foreignKey:CustomerID"`
+	Orders    []Order           `json:"orders,omitempty" gorm:"foreignKey:CustomerID;constraint:-"` // No foreign key, older orders may name unknown customers
 }
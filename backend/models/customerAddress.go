@@ -0,0 +1,41 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CustomerAddress is a saved delivery address of a customer
+type CustomerAddress struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	CustomerID    uint           `json:"customer_id" gorm:"not null;index;uniqueIndex:idx_customer_addresses_default,where:is_default AND deleted_at IS NULL"`
+	Label         string         `json:"label"` // e.g. "Home", "Office"
+	Line1         string         `json:"line1" gorm:"not null"`
+	Line2         string         `json:"line2"`
+	City          string         `json:"city"`
+	Landmark      string         `json:"landmark"` // e.g. "Opposite the temple"
+	Latitude      *float64       `json:"latitude"`
+	Longitude     *float64       `json:"longitude"`
+	DeliveryNotes string         `json:"delivery_notes"` // e.g. "Ring twice, dog in garden"
+	IsDefault     bool           `json:"is_default" gorm:"default:false"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// Format renders the address on one line, e.g. "12 Galle Rd, Flat 3, Colombo (near the temple)"
+func (a CustomerAddress) Format() string {
+	parts := []string{}
+	for _, part := range []string{a.Line1, a.Line2, a.City} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	address := strings.Join(parts, ", ")
+	if landmark := strings.TrimSpace(a.Landmark); landmark != "" {
+		address += " (" + landmark + ")"
+	}
+	return address
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeliveryZone is an area the shop delivers to, with its own fee, minimum
+// order value and delivery time
+type DeliveryZone struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	Name             string         `json:"name" gorm:"not null"`
+	Boundary         GeoBoundary    `json:"boundary" gorm:"not null"`
+	DeliveryFee      Money          `json:"delivery_fee" gorm:"not null;default:0"`
+	MinimumOrder     Money          `json:"minimum_order" gorm:"not null;default:0"` // Order value before the fee and exclusive tax
+	EstimatedMinutes int            `json:"estimated_minutes" gorm:"not null;default:0"`
+	IsActive         bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// GeoBoundary is a GeoJSON Polygon or MultiPolygon: polygons of rings of
+// [longitude, latitude] points. The first ring of a polygon is its outline,
+// the others are holes.
+type GeoBoundary [][][][2]float64
+
+var ErrInvalidBoundary = errors.New("boundary must be a GeoJSON Polygon or MultiPolygon")
+
+// MarshalJSON writes the boundary as a GeoJSON geometry
+func (b GeoBoundary) MarshalJSON() ([]byte, error) {
+	if len(b) == 1 {
+		return json.Marshal(map[string]interface{}{"type": "Polygon", "coordinates": b[0]})
+	}
+	return json.Marshal(map[string]interface{}{"type": "MultiPolygon", "coordinates": [][][][2]float64(b)})
+}
+
+// UnmarshalJSON reads a GeoJSON Polygon or MultiPolygon, bare or wrapped in a Feature
+func (b *GeoBoundary) UnmarshalJSON(data []byte) error {
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+		Geometry    json.RawMessage `json:"geometry"`
+	}
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return ErrInvalidBoundary
+	}
+
+	switch geometry.Type {
+	case "Feature":
+		return b.UnmarshalJSON(geometry.Geometry)
+	case "Polygon":
+		var polygon [][][2]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil {
+			return ErrInvalidBoundary
+		}
+		*b = GeoBoundary{polygon}
+	case "MultiPolygon":
+		var polygons [][][][2]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygons); err != nil {
+			return ErrInvalidBoundary
+		}
+		*b = GeoBoundary(polygons)
+	default:
+		return ErrInvalidBoundary
+	}
+	return b.Validate()
+}
+
+// Validate checks every ring has at least three corners inside valid
+// coordinates, and closes rings that do not end on their first point
+func (b GeoBoundary) Validate() error {
+	if len(b) == 0 {
+		return ErrInvalidBoundary
+	}
+	for p, polygon := range b {
+		if len(polygon) == 0 {
+			return ErrInvalidBoundary
+		}
+		for r, ring := range polygon {
+			for _, point := range ring {
+				if point[0] < -180 || point[0] > 180 || point[1] < -90 || point[1] > 90 {
+					return fmt.Errorf("boundary point %v is not a valid [longitude, latitude]", point)
+				}
+			}
+			if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+				ring = append(ring, ring[0])
+				b[p][r] = ring
+			}
+			if len(ring) < 4 {
+				return fmt.Errorf("boundary rings need at least three corners")
+			}
+		}
+	}
+	return nil
+}
+
+// boundaryTolerance is how far, in degrees, a point may be from a ring's edge
+// to count as on it (about a centimetre)
+const boundaryTolerance = 1e-7
+
+// Contains reports whether the point lies inside the boundary: inside the
+// outline of one of its polygons and outside that polygon's holes. Points on
+// an edge or corner, of the outline or of a hole, belong to the zone.
+func (b GeoBoundary) Contains(latitude, longitude float64) bool {
+	for _, polygon := range b {
+		if len(polygon) == 0 {
+			continue
+		}
+		if !ringContains(polygon[0], latitude, longitude) && !onRing(polygon[0], latitude, longitude) {
+			continue
+		}
+		inHole := false
+		for _, hole := range polygon[1:] {
+			if ringContains(hole, latitude, longitude) && !onRing(hole, latitude, longitude) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContains casts a ray from the point and counts the edges it crosses
+func ringContains(ring [][2]float64, latitude, longitude float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > latitude) != (yj > latitude) && longitude < (xj-xi)*(latitude-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// onRing reports whether the point lies on one of the ring's edges
+func onRing(ring [][2]float64, latitude, longitude float64) bool {
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		if onSegment(ring[j], ring[i], latitude, longitude) {
+			return true
+		}
+	}
+	return false
+}
+
+// onSegment reports whether the point is within boundaryTolerance of the edge from a to b
+func onSegment(a, b [2]float64, latitude, longitude float64) bool {
+	if longitude < math.Min(a[0], b[0])-boundaryTolerance || longitude > math.Max(a[0], b[0])+boundaryTolerance ||
+		latitude < math.Min(a[1], b[1])-boundaryTolerance || latitude > math.Max(a[1], b[1])+boundaryTolerance {
+		return false
+	}
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return math.Hypot(longitude-a[0], latitude-a[1]) <= boundaryTolerance
+	}
+	return math.Abs(dx*(latitude-a[1])-dy*(longitude-a[0]))/length <= boundaryTolerance
+}
+
+// GormDataType stores the boundary as JSON
+func (GeoBoundary) GormDataType() string {
+	return "jsonb"
+}
+
+// Value stores the boundary as a GeoJSON geometry
+func (b GeoBoundary) Value() (driver.Value, error) {
+	data, err := b.MarshalJSON()
+	return string(data), err
+}
+
+func (b *GeoBoundary) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return b.UnmarshalJSON(v)
+	case string:
+		return b.UnmarshalJSON([]byte(v))
+	case nil:
+		*b = nil
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into GeoBoundary", value)
+}
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// square is a closed ring of [longitude, latitude] points
+func square(west, south, east, north float64) [][2]float64 {
+	return [][2]float64{{west, south}, {east, south}, {east, north}, {west, north}, {west, south}}
+}
+
+func TestGeoBoundaryContains(t *testing.T) {
+	// A 10 x 10 degree square with a 2 x 2 hole in the middle
+	withHole := GeoBoundary{{square(0, 0, 10, 10), square(4, 4, 6, 6)}}
+	// A triangle with a diagonal edge from (10, 0) to (0, 10)
+	triangle := GeoBoundary{{{{0, 0}, {10, 0}, {0, 10}, {0, 0}}}}
+	// Colombo-sized zone with a diagonal edge on real coordinates
+	colombo := GeoBoundary{{{{79.8, 6.8}, {79.9, 6.8}, {79.8, 6.9}, {79.8, 6.8}}}}
+	// Two separate squares
+	multi := GeoBoundary{{square(0, 0, 1, 1)}, {square(5, 5, 6, 6)}}
+
+	tests := []struct {
+		name      string
+		boundary  GeoBoundary
+		latitude  float64
+		longitude float64
+		want      bool
+	}{
+		{"inside", withHole, 2, 2, true},
+		{"outside", withHole, 2, 11, false},
+		{"just outside the east edge", withHole, 5, 10.0001, false},
+		{"on the west edge", withHole, 5, 0, true},
+		{"on the east edge", withHole, 5, 10, true},
+		{"on the south edge", withHole, 0, 5, true},
+		{"on the north edge", withHole, 10, 5, true},
+		{"on the south west corner", withHole, 0, 0, true},
+		{"on the north east corner", withHole, 10, 10, true},
+		{"on the north west corner", withHole, 10, 0, true},
+		{"on the line of an edge, past the corner", withHole, 0, 11, false},
+		{"in the hole", withHole, 5, 5, false},
+		{"on the hole's edge", withHole, 4, 5, true},
+		{"on the hole's corner", withHole, 6, 6, true},
+		{"between the hole and the outline", withHole, 5, 7, true},
+		{"on a diagonal edge", triangle, 5, 5, true},
+		{"just outside a diagonal edge", triangle, 5.001, 5, false},
+		{"on a diagonal edge, real coordinates", colombo, 6.85, 79.85, true},
+		{"inside, real coordinates", colombo, 6.81, 79.81, true},
+		{"first polygon", multi, 0.5, 0.5, true},
+		{"second polygon", multi, 5.5, 5.5, true},
+		{"between the polygons", multi, 3, 3, false},
+		{"empty boundary", GeoBoundary{}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.boundary.Contains(tt.latitude, tt.longitude); got != tt.want {
+				t.Errorf("Contains(%v, %v) = %v, want %v", tt.latitude, tt.longitude, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoBoundaryValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		boundary GeoBoundary
+		wantErr  bool
+	}{
+		{"closed ring", GeoBoundary{{square(0, 0, 1, 1)}}, false},
+		{"unclosed ring", GeoBoundary{{{{0, 0}, {1, 0}, {1, 1}}}}, false},
+		{"unclosed hole", GeoBoundary{{square(0, 0, 10, 10), {{4, 4}, {6, 4}, {6, 6}}}}, false},
+		{"two corners", GeoBoundary{{{{0, 0}, {1, 0}}}}, true},
+		{"two corners, closed", GeoBoundary{{{{0, 0}, {1, 0}, {0, 0}}}}, true},
+		{"empty ring", GeoBoundary{{{}}}, true},
+		{"no rings", GeoBoundary{{}}, true},
+		{"no polygons", GeoBoundary{}, true},
+		{"longitude out of range", GeoBoundary{{{{0, 0}, {181, 0}, {1, 1}}}}, true},
+		{"latitude out of range", GeoBoundary{{{{0, 0}, {1, -91}, {1, 1}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.boundary.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			for _, polygon := range tt.boundary {
+				for _, ring := range polygon {
+					if ring[0] != ring[len(ring)-1] {
+						t.Errorf("ring %v was not closed", ring)
+					}
+				}
+			}
+		})
+	}
+}
+
+// GeoJSON puts longitude first. Colombo is at latitude 6.9, longitude 79.85.
+func TestGeoBoundaryCoordinateOrder(t *testing.T) {
+	var lngLat GeoBoundary
+	if err := json.Unmarshal([]byte(`{"type": "Polygon", "coordinates": [[[79.8, 6.8], [79.9, 6.8], [79.9, 7.0], [79.8, 7.0]]]}`), &lngLat); err != nil {
+		t.Fatal(err)
+	}
+	if !lngLat.Contains(6.9, 79.85) {
+		t.Error("a [longitude, latitude] boundary must contain Colombo")
+	}
+
+	// The same zone typed as [latitude, longitude] is a valid polygon
+	// somewhere else, so it cannot be caught, and Colombo is outside it
+	var latLng GeoBoundary
+	if err := json.Unmarshal([]byte(`{"type": "Polygon", "coordinates": [[[6.8, 79.8], [6.8, 79.9], [7.0, 79.9], [7.0, 79.8]]]}`), &latLng); err != nil {
+		t.Fatal(err)
+	}
+	if latLng.Contains(6.9, 79.85) {
+		t.Error("a [latitude, longitude] boundary must not contain Colombo")
+	}
+	if !latLng.Contains(79.85, 6.9) {
+		t.Error("a [latitude, longitude] boundary contains the swapped point")
+	}
+
+	// Swapped longitudes beyond 90 are out of range as latitudes
+	var swapped GeoBoundary
+	err := json.Unmarshal([]byte(`{"type": "Polygon", "coordinates": [[[-33.9, 151.1], [-33.9, 151.3], [-33.8, 151.3], [-33.8, 151.1]]]}`), &swapped)
+	if err == nil {
+		t.Error("a [latitude, longitude] boundary with longitudes beyond 90 must be refused")
+	}
+}
+
+func TestGeoBoundaryJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		polygons int
+		wantErr  bool
+	}{
+		{"polygon", `{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}`, 1, false},
+		{"multipolygon", `{"type": "MultiPolygon", "coordinates": [[[[0, 0], [1, 0], [1, 1]]], [[[5, 5], [6, 5], [6, 6]]]]}`, 2, false},
+		{"feature", `{"type": "Feature", "properties": {}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1]]]}}`, 1, false},
+		{"point", `{"type": "Point", "coordinates": [79.85, 6.9]}`, 0, true},
+		{"bad coordinates", `{"type": "Polygon", "coordinates": [[["a", "b"]]]}`, 0, true},
+		{"not an object", `[[0, 0], [1, 0], [1, 1]]`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var boundary GeoBoundary
+			err := json.Unmarshal([]byte(tt.json), &boundary)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unmarshal error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(boundary) != tt.polygons {
+				t.Fatalf("got %d polygons, want %d", len(boundary), tt.polygons)
+			}
+
+			// Value writes what Scan reads back
+			value, err := boundary.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var scanned GeoBoundary
+			if err := scanned.Scan(value); err != nil {
+				t.Fatal(err)
+			}
+			if len(scanned) != len(boundary) || len(scanned[0][0]) != len(boundary[0][0]) {
+				t.Errorf("round trip changed %v into %v", boundary, scanned)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// DocumentSequence holds the last number handed out for a document series
+// (invoices, credit notes, ...) per prefix, year and store
+type DocumentSequence struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Prefix    string    `json:"prefix" gorm:"not null;uniqueIndex:idx_document_sequences_series"`
+	Year      int       `json:"year" gorm:"not null;uniqueIndex:idx_document_sequences_series"`
+	StoreCode string    `json:"store_code" gorm:"not null;default:'';uniqueIndex:idx_document_sequences_series"`
+	LastValue int64     `json:"last_value" gorm:"not null;default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Driver is a rider on the shop's delivery roster
+type Driver struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Name          string         `json:"name" gorm:"not null"`
+	Phone         string         `json:"phone"`                                            // E.164, e.g. +94771234567
+	Vehicle       string         `json:"vehicle"`                                          // e.g. "Bike WP BCD-1234"
+	Status        string         `json:"status" gorm:"not null;default:'available';index"` // available, on_delivery, off_duty
+	LastLatitude  *float64       `json:"last_latitude"`                                    // From the latest location ping
+	LastLongitude *float64       `json:"last_longitude"`
+	LastSeenAt    *time.Time     `json:"last_seen_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// DriverLocation is one location ping sent from a driver's phone
+type DriverLocation struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	DriverID   uint      `json:"driver_id" gorm:"not null;index:idx_driver_locations_recorded,priority:1"`
+	Latitude   float64   `json:"latitude" gorm:"not null"`
+	Longitude  float64   `json:"longitude" gorm:"not null"`
+	RecordedAt time.Time `json:"recorded_at" gorm:"not null;index:idx_driver_locations_recorded,priority:2"` // When the phone took the fix
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DeliveryAssignment is a delivery order given to a driver, from assignment
+// until the driver hands in the cash collected for it
+type DeliveryAssignment struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	OrderID        uint       `json:"order_id" gorm:"not null;index;uniqueIndex:idx_delivery_assignments_open_order,where:status = 'assigned' OR status = 'out_for_delivery'"` // One open assignment per order
+	DriverID       uint       `json:"driver_id" gorm:"not null;index"`
+	Status         string     `json:"status" gorm:"not null;default:'assigned';index"` // assigned, out_for_delivery, delivered, settled, cancelled
+	AutoAssigned   bool       `json:"auto_assigned" gorm:"default:false"`
+	AssignedBy     string     `json:"assigned_by"`
+	AssignedAt     time.Time  `json:"assigned_at" gorm:"not null"`
+	DispatchedAt   *time.Time `json:"dispatched_at"` // When the order went out for delivery
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CancelledAt    *time.Time `json:"cancelled_at"`                              // Order cancelled, or given to another driver
+	CashToCollect  Money      `json:"cash_to_collect" gorm:"not null;default:0"` // Cash due on the order when the driver left
+	CashCollected  *Money     `json:"cash_collected"`                            // Set when the driver settles
+	CashDifference *Money     `json:"cash_difference"`                           // CashCollected - cash due at settlement; negative when short
+	PaymentID      *uint      `json:"payment_id"`                                // Cash payment recorded for the collection
+	SettledAt      *time.Time `json:"settled_at"`
+	SettledBy      string     `json:"settled_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	Order  *Order  `json:"order,omitempty" gorm:"foreignKey:OrderID;constraint:-"`
+	Driver *Driver `json:"driver,omitempty" gorm:"foreignKey:DriverID;constraint:-"`
+}
@@ -12,16 +12,23 @@ type Invoice struct {
 	OrderID        uint           `json:"order_id" gorm:"not null;uniqueIndex"`
 	InvoiceNumber  string         `json:"invoice_number" gorm:"unique;not null"`
 	InvoiceDate    time.Time      `json:"invoice_date" gorm:"not null"`
-	SubtotalAmount float64        `json:"subtotal_amount" gorm:"not null"`
-	TaxAmount      float64        `json:"tax_amount" gorm:"not null"`
-	TotalAmount    float64        `json:"total_amount" gorm:"not null"`
-	PaymentStatus  string         `json:"payment_status" gorm:"not null;default:'pending'"` // pending, paid, overdue, cancelled
-	PaymentDate    *time.Time     `json:"payment_date"`
+	SubtotalAmount Money          `json:"subtotal_amount" gorm:"not null"`
+	TaxAmount      Money          `json:"tax_amount" gorm:"not null"`
+	TotalAmount    Money          `json:"total_amount" gorm:"not null"`
+	PaymentStatus  string         `json:"payment_status" gorm:"not null;default:'pending'"` // pending, partially_paid, paid, overpaid, overdue, cancelled, refunded
+	PaymentDate    *time.Time     `json:"payment_date"`                                     // When the invoice became fully paid
+	AmountPaid     Money          `json:"amount_paid" gorm:"not null;default:0"`            // Sum of the payments that are not reversed
+	RefundedAmount Money          `json:"refunded_amount" gorm:"not null;default:0"`        // Total of the credit notes, positive
+	VoidedAt       *time.Time     `json:"voided_at"`                                        // Set when an unpaid invoice is cancelled with its order
+	VoidReason     string         `json:"void_reason"`
 	Notes          string         `json:"notes"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	// Relationships
-	Order Order `json:"order" gorm:"foreignKey:OrderID"`
+	Order       Order        `json:"order" gorm:"foreignKey:OrderID"`
+	Taxes       []InvoiceTax `json:"taxes" gorm:"foreignKey:InvoiceID"`
+	CreditNotes []CreditNote `json:"credit_notes" gorm:"foreignKey:InvoiceID"`
+	Payments    []Payment    `json:"payments" gorm:"foreignKey:InvoiceID"`
 }
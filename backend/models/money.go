@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units (cents). It is stored in PostgreSQL as
+// NUMERIC(12,2) and encoded in JSON as a decimal string such as "1250.00", so
+// totals never pick up floating point noise like 2199.9999999.
+//
+// All rounding to cents goes through RoundRat: half away from zero.
+type Money int64
+
+// MaxMoney is the largest amount a NUMERIC(12,2) column holds, 9999999999.99
+const MaxMoney Money = 999999999999
+
+// MoneyFromFloat converts a float amount (e.g. a legacy value) to Money, rounding to cents
+func MoneyFromFloat(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+
+// ParseMoney parses a decimal string such as "12.5", "-3.25" or "1000".
+// More than two decimals are rounded to cents, and amounts a NUMERIC(12,2)
+// column cannot hold are refused.
+func ParseMoney(value string) (Money, error) {
+	return parseMoney(value, MaxMoney)
+}
+
+// parseMoney parses a decimal string, refusing amounts beyond limit either way
+func parseMoney(value string, limit Money) (Money, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return 0, fmt.Errorf("invalid money amount %q", value)
+	}
+	cents := rat.Mul(rat, big.NewRat(100, 1))
+	if new(big.Rat).Abs(cents).Cmp(limit.Rat()) > 0 {
+		return 0, fmt.Errorf("money amount %q is out of range", value)
+	}
+	return RoundRat(cents), nil
+}
+
+// RoundRat rounds an amount expressed in cents to whole cents, half away from zero
+func RoundRat(cents *big.Rat) Money {
+	num := new(big.Int).Set(cents.Num())
+	den := cents.Denom()
+	negative := num.Sign() < 0
+	num.Abs(num)
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(den) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if negative {
+		quo.Neg(quo)
+	}
+	return Money(quo.Int64())
+}
+
+// Rat returns the amount in cents as an exact rational
+func (m Money) Rat() *big.Rat {
+	return new(big.Rat).SetInt64(int64(m))
+}
+
+// Times multiplies the amount by a quantity, refusing results a NUMERIC(12,2)
+// column cannot hold rather than wrapping around
+func (m Money) Times(quantity int) (Money, error) {
+	if quantity != 0 && abs(int64(m)) > int64(MaxMoney)/abs(int64(quantity)) {
+		return 0, fmt.Errorf("money amount %s x %d is out of range", m, quantity)
+	}
+	return m * Money(quantity), nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Prorate returns the share part/whole of the amount, rounded to cents
+func (m Money) Prorate(part, whole int64) Money {
+	return RoundRat(new(big.Rat).Mul(m.Rat(), big.NewRat(part, whole)))
+}
+
+// RoundTo rounds the amount to a multiple of step, half away from zero, e.g.
+// to the smallest coin for cash
+func (m Money) RoundTo(step Money) Money {
+	if step <= 0 {
+		return m
+	}
+	return RoundRat(big.NewRat(int64(m), int64(step))) * step
+}
+
+// Percent returns rate percent of the amount, rounded to cents
+func (m Money) Percent(rate float64) Money {
+	return RoundRat(PercentOf(m.Rat(), rate))
+}
+
+// PercentOf returns rate percent of an exact amount, without rounding
+func PercentOf(cents *big.Rat, rate float64) *big.Rat {
+	return new(big.Rat).Mul(cents, RateRat(rate))
+}
+
+// RateRat converts a percentage to an exact fraction, e.g. 12.5 to 1/8. The
+// decimal representation is used, so 0.1 stays exactly one tenth.
+func RateRat(rate float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	return r.Quo(r, big.NewRat(100, 1))
+}
+
+// Float64 returns the amount in major units, for display and statistics only
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String formats the amount with two decimals, e.g. "1250.00" or "-3.50"
+func (m Money) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/100, value%100)
+}
+
+// MarshalJSON encodes the amount as a decimal string
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts a decimal string ("12.50") or a plain JSON number (12.5)
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := strings.TrimSpace(string(data))
+	if text == "null" {
+		return nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// GormDataType stores Money as an exact decimal column
+func (Money) GormDataType() string {
+	return "numeric(12,2)"
+}
+
+// Value implements driver.Valuer
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan implements sql.Scanner for NUMERIC, float and integer columns
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		// Sums and other computed columns may exceed NUMERIC(12,2)
+		parsed, err := parseMoney(string(v), math.MaxInt64-1)
+		*m = parsed
+		return err
+	case string:
+		parsed, err := parseMoney(v, math.MaxInt64-1)
+		*m = parsed
+		return err
+	case float64:
+		*m = MoneyFromFloat(v)
+		return nil
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into Money", value)
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Money
+		wantErr bool
+	}{
+		{"12.50", 1250, false},
+		{"12.5", 1250, false},
+		{"1000", 100000, false},
+		{" 3.25 ", 325, false},
+		{"-3.25", -325, false},
+		{"-0.01", -1, false},
+		{"0", 0, false},
+		{"1.005", 101, false},   // More than two decimals round half away from zero
+		{"1.0049", 100, false},  // ... but only from the half
+		{"-1.005", -101, false}, // Negative halves round away from zero too
+		{"2.675", 268, false},   // Exact decimal, no float error (2.67499... as a float)
+		{"9999999999.99", MaxMoney, false},
+		{"-9999999999.99", -MaxMoney, false},
+		{"10000000000", 0, true}, // Beyond NUMERIC(12,2)
+		{"-10000000000.00", 0, true},
+		{"92233720368547758.08", 0, true}, // Beyond int64 cents
+		{"1e30", 0, true},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"12,50", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMoney(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+// Rounding is half away from zero ("half up"), not banker's half-even: halves
+// on even cents go up too
+func TestRoundRat(t *testing.T) {
+	tests := []struct {
+		cents string
+		want  Money
+		even  Money // What half-even would give, for reference
+	}{
+		{"0.5", 1, 0},
+		{"1.5", 2, 2},
+		{"2.5", 3, 2},
+		{"-0.5", -1, 0},
+		{"-2.5", -3, -2},
+		{"2.4999", 2, 2},
+		{"2.5001", 3, 3},
+		{"7/3", 2, 2},
+		{"-7/3", -2, -2},
+		{"0", 0, 0},
+	}
+	for _, tt := range tests {
+		cents, ok := new(big.Rat).SetString(tt.cents)
+		if !ok {
+			t.Fatalf("bad test value %q", tt.cents)
+		}
+		if got := RoundRat(cents); got != tt.want {
+			t.Errorf("RoundRat(%s) = %d, want %d (half-even would give %d)", tt.cents, got, tt.want, tt.even)
+		}
+	}
+}
+
+func TestMoneyRoundTo(t *testing.T) {
+	tests := []struct {
+		amount Money
+		step   Money
+		want   Money
+	}{
+		{1252, 500, 1500}, // 12.52 to the nearest 5.00
+		{1249, 500, 1000},
+		{1250, 500, 1500}, // Half goes up
+		{-1250, 500, -1500},
+		{1237, 10, 1240},
+		{1235, 10, 1240},
+		{1234, 10, 1230},
+		{1234, 0, 1234}, // No rounding
+		{1234, -5, 1234},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.RoundTo(tt.step); got != tt.want {
+			t.Errorf("%s.RoundTo(%s) = %s, want %s", tt.amount, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyProrateAndPercent(t *testing.T) {
+	if got := Money(1000).Prorate(1, 3); got != 333 {
+		t.Errorf("10.00 * 1/3 = %s, want 3.33", got)
+	}
+	if got := Money(1000).Prorate(2, 3); got != 667 {
+		t.Errorf("10.00 * 2/3 = %s, want 6.67", got)
+	}
+	if got := Money(1999).Percent(12.5); got != 250 {
+		t.Errorf("12.5%% of 19.99 = %s, want 2.50", got)
+	}
+	if got := Money(10).Percent(15); got != 2 {
+		t.Errorf("15%% of 0.10 = %s, want 0.02", got)
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := map[Money]string{
+		0:        "0.00",
+		5:        "0.05",
+		-5:       "-0.05",
+		125000:   "1250.00",
+		-350:     "-3.50",
+		MaxMoney: "9999999999.99",
+	}
+	for amount, want := range tests {
+		if got := amount.String(); got != want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(amount), got, want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{Money(-1999)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"amount":"-19.99"}` {
+		t.Errorf("marshalled %s", data)
+	}
+
+	tests := []struct {
+		json    string
+		want    Money
+		wantErr bool
+	}{
+		{`"12.50"`, 1250, false},
+		{`12.5`, 1250, false},
+		{`-0.1`, -10, false},
+		{`"0.125"`, 13, false},
+		{`null`, 777, false}, // Leaves the value alone
+		{`"ten"`, 0, true},
+		{`"1e12"`, 0, true},
+	}
+	for _, tt := range tests {
+		amount := Money(777)
+		err := json.Unmarshal([]byte(tt.json), &amount)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unmarshal %s error = %v, want error %v", tt.json, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && amount != tt.want {
+			t.Errorf("unmarshal %s = %d, want %d", tt.json, amount, tt.want)
+		}
+	}
+}
+
+// Value writes what PostgreSQL stores in NUMERIC(12,2) and Scan reads it back
+func TestMoneyValueScanRoundTrip(t *testing.T) {
+	for _, amount := range []Money{0, 1, -1, 99, 1250, -1250, 123456789, MaxMoney, -MaxMoney} {
+		value, err := amount.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, ok := value.(string)
+		if !ok {
+			t.Fatalf("Value() returned %T, want string", value)
+		}
+
+		var fromBytes, fromString Money
+		if err := fromBytes.Scan([]byte(text)); err != nil || fromBytes != amount {
+			t.Errorf("Scan([]byte(%q)) = %d, %v; want %d", text, fromBytes, err, amount)
+		}
+		if err := fromString.Scan(text); err != nil || fromString != amount {
+			t.Errorf("Scan(%q) = %d, %v; want %d", text, fromString, err, amount)
+		}
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    Money
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{[]byte("1250.00"), 125000, false},
+		{"-3.50", -350, false},
+		{[]byte("12345678901234.56"), 1234567890123456, false}, // SUM() can exceed NUMERIC(12,2)
+		{float64(19.99), 1999, false},                          // Legacy double precision columns
+		{float64(0.1 + 0.2), 30, false},
+		{int64(12), 1200, false},
+		{[]byte("x"), 0, true},
+		{true, 0, true},
+	}
+	for _, tt := range tests {
+		var amount Money
+		err := amount.Scan(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Scan(%v) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && amount != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.value, amount, tt.want)
+		}
+	}
+}
+
+func TestMoneyTimes(t *testing.T) {
+	tests := []struct {
+		amount   Money
+		quantity int
+		want     Money
+		wantErr  bool
+	}{
+		{1250, 3, 3750, false},
+		{1250, 0, 0, false},
+		{-350, 2, -700, false},
+		{350, -2, -700, false},
+		{MaxMoney, 1, MaxMoney, false},
+		{MaxMoney / 1000, 1000, MaxMoney / 1000 * 1000, false},
+		{MaxMoney, 2, 0, true},
+		{1250, 1 << 60, 0, true}, // Would wrap around in int64
+		{-MaxMoney, 2, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.amount.Times(tt.quantity)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s.Times(%d) error = %v, want error %v", tt.amount, tt.quantity, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%s.Times(%d) = %s, want %s", tt.amount, tt.quantity, got, tt.want)
+		}
+	}
+}
@@ -8,15 +8,35 @@ import (
 
 // Order represents an order in the system
 type Order struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	CustomerID  uint           `json:"customer_id" gorm:"not null"`
-	OrderDate   time.Time      `json:"order_date" gorm:"not null"`
-	TotalAmount float64        `json:"total_amount" gorm:"not null"`
-	Tax         float64        `json:"tax" gorm:"not null"`
-	OrderStatus string         `json:"order_status" gorm:"not null;default:'pending'"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	CustomerID        uint           `json:"customer_id" gorm:"not null"`
+	OrderDate         time.Time      `json:"order_date" gorm:"not null"`
+	Subtotal          Money          `json:"subtotal" gorm:"not null;default:0"` // Net of tax
+	TotalAmount       Money          `json:"total_amount" gorm:"not null"`
+	Tax               Money          `json:"tax" gorm:"not null"`
+	OrderStatus       string         `json:"order_status" gorm:"not null;default:'pending'"`
+	FulfilmentType    string         `json:"fulfilment_type" gorm:"not null;default:'takeaway';index"` // dine_in, takeaway, delivery
+	TableNumber       string         `json:"table_number"`                                             // Dine-in only
+	PickupTime        *time.Time     `json:"pickup_time"`                                              // Takeaway only, when the customer will collect
+	Courier           string         `json:"courier"`                                                  // Delivery only, e.g. in_house or a delivery app
+	DeliveryAddressID *uint          `json:"delivery_address_id" gorm:"index"`                         // Saved address the order goes to, if any
+	DeliveryAddress   string         `json:"delivery_address"`                                         // Address snapshot taken at order time
+	DeliveryLatitude  *float64       `json:"delivery_latitude"`
+	DeliveryLongitude *float64       `json:"delivery_longitude"`
+	DeliveryNotes     string         `json:"delivery_notes"`
+	DeliveryZoneID    *uint          `json:"delivery_zone_id"`
+	DeliveryFee       Money          `json:"delivery_fee" gorm:"not null;default:0"` // Also billed as a delivery fee line
+	EstimatedMinutes  int            `json:"estimated_minutes"`                      // Delivery time of the zone
+	DriverID          *uint          `json:"driver_id" gorm:"index"`                 // In-house driver the order is assigned to
+	ConfirmedAt       *time.Time     `json:"confirmed_at"`                           // Set when the order first enters each stage
+	PreparingAt       *time.Time     `json:"preparing_at"`
+	ReadyAt           *time.Time     `json:"ready_at"`
+	OutForDeliveryAt  *time.Time     `json:"out_for_delivery_at"`
+	DeliveredAt       *time.Time     `json:"delivered_at"`
+	CancelledAt       *time.Time     `json:"cancelled_at"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	// Relationships
 	// Customer   Customer    `json:"customer" gorm:"foreignKey:CustomerID"`
@@ -8,16 +8,31 @@ import (
 
 // OrderItem represents items in an order
 type OrderItem struct {
-	ID         uint           `json:"id" gorm:"primaryKey"`
-	OrderID    uint           `json:"order_id" gorm:"not null"`
-	ItemID     uint           `json:"item_id" gorm:"not null"`
-	Quantity   int            `json:"quantity" gorm:"not null"`
-	TotalPrice float64        `json:"total_price" gorm:"not null"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	OrderID         uint           `json:"order_id" gorm:"not null"`
+	ItemID          uint           `json:"item_id" gorm:"not null"`
+	PizzaID         *uint          `json:"pizza_id" gorm:"index"`    // Ordered pizza variant (size/base), if any
+	BeverageID      *uint          `json:"beverage_id" gorm:"index"` // Ordered beverage variant (size), if any
+	ToppingID       *uint          `json:"topping_id" gorm:"index"`  // Topping sold as its own line, if any
+	Description     string         `json:"description"`              // e.g. "Large thin crust Margherita", snapshot at order time
+	Quantity        int            `json:"quantity" gorm:"not null"`
+	UnitPrice       Money          `json:"unit_price" gorm:"not null;default:0"`     // Menu price snapshot taken at order time
+	ToppingsPrice   Money          `json:"toppings_price" gorm:"not null;default:0"` // Extra toppings per unit
+	TotalPrice      Money          `json:"total_price" gorm:"not null"`              // (UnitPrice + ToppingsPrice) * Quantity
+	NetAmount       Money          `json:"net_amount" gorm:"not null;default:0"`     // TotalPrice without tax
+	TaxAmount       Money          `json:"tax_amount" gorm:"not null;default:0"`
+	ClientUnitPrice *Money         `json:"client_unit_price,omitempty"` // Price quoted by the client, kept only when it disagreed
+	PriceMismatch   bool           `json:"price_mismatch" gorm:"default:false"`
+	DeliveryFee     bool           `json:"delivery_fee" gorm:"default:false"` // The order's delivery fee rather than a menu item
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	// Relationships
-	Order Order `json:"order" gorm:"foreignKey:OrderID"`
-	Item  Item  `json:"item" gorm:"foreignKey:ItemID"`
+	Order    Order              `json:"order" gorm:"foreignKey:OrderID"`
+	Item     Item               `json:"item" gorm:"foreignKey:ItemID"`
+	Pizza    *Pizza             `json:"pizza,omitempty" gorm:"foreignKey:PizzaID"`
+	Beverage *Beverage          `json:"beverage,omitempty" gorm:"foreignKey:BeverageID"`
+	Topping  *Topping           `json:"topping,omitempty" gorm:"foreignKey:ToppingID"`
+	Toppings []OrderItemTopping `json:"toppings" gorm:"foreignKey:OrderItemID"`
 }
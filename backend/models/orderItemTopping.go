@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrderItemTopping is a topping modifier on an order line: an extra topping
+// added to the pizza or one of its standard toppings taken off
+type OrderItemTopping struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	OrderItemID uint           `json:"order_item_id" gorm:"not null;index"`
+	ToppingID   uint           `json:"topping_id" gorm:"not null"`
+	Name        string         `json:"name" gorm:"not null"`                  // Topping name snapshot
+	Action      string         `json:"action" gorm:"not null;default:'add'"`  // add, remove
+	Quantity    int            `json:"quantity" gorm:"not null;default:1"`    // Portions per pizza
+	UnitPrice   Money          `json:"unit_price" gorm:"not null;default:0"`  // Topping price snapshot, 0 for removals
+	TotalPrice  Money          `json:"total_price" gorm:"not null;default:0"` // UnitPrice * Quantity, per pizza
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Topping *Topping `json:"topping,omitempty" gorm:"foreignKey:ToppingID"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// OrderStatusHistory records one order status change, the order's timeline
+type OrderStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	OrderID    uint      `json:"order_id" gorm:"not null;index"`
+	FromStatus string    `json:"from_status"` // Empty for the entry written when the order is placed
+	ToStatus   string    `json:"to_status" gorm:"not null"`
+	ChangedBy  string    `json:"changed_by"`
+	Note       string    `json:"note"`
+	ChangedAt  time.Time `json:"changed_at" gorm:"not null"`
+}
+
+// TableName keeps the table name singular, as in order_status_history
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
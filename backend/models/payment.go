@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Payment is money received against an invoice. An invoice may be settled by
+// several payments (split bills, cash plus card); reversed payments no longer count.
+// Two methods are written by the system: cash_rounding is the difference
+// between the balance and the rounded cash amount that settled it, legacy
+// stands for invoices marked paid before payments were recorded.
+type Payment struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	InvoiceID      uint           `json:"invoice_id" gorm:"not null;index"`
+	Method         string         `json:"method" gorm:"not null"`             // cash, card, bank_transfer, voucher
+	Amount         Money          `json:"amount" gorm:"not null"`             // Applied to the invoice
+	Tendered       Money          `json:"tendered" gorm:"not null;default:0"` // Cash handed over, 0 for other methods
+	Change         Money          `json:"change" gorm:"not null;default:0"`   // Tendered - Amount
+	Reference      string         `json:"reference"`                          // Card slip, transfer reference, payer name, ...
+	RoundingForID  *uint          `json:"rounding_for_id" gorm:"index"`       // For cash_rounding: the cash payment it belongs to
+	ReceivedBy     string         `json:"received_by"`
+	CashSessionID  *uint          `json:"cash_session_id" gorm:"index"` // Drawer session the payment was taken in
+	PaidAt         time.Time      `json:"paid_at" gorm:"not null"`
+	Reversed       bool           `json:"reversed" gorm:"not null;default:false"`
+	ReversedAt     *time.Time     `json:"reversed_at"`
+	ReversedBy     string         `json:"reversed_by"`
+	ReversalReason string         `json:"reversal_reason"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Rounding *Payment `json:"rounding,omitempty" gorm:"foreignKey:RoundingForID"`
+}
@@ -13,13 +13,13 @@ type Pizza struct {
 	Name      string         `json:"name" gorm:"not null"`
 	Size      string         `json:"size" gorm:"not null"`
 	BaseType  string         `json:"base_type" gorm:"not null"`
-	Price     float64        `json:"price" gorm:"not null"`
+	Price     Money          `json:"price" gorm:"not null"`
 	IsActive  bool           `json:"is_active" gorm:"default:true"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	// Relationships
-	// Item     Item             `json:"item" gorm:"foreignKey:ItemID"`
+	Item *Item `json:"item,omitempty" gorm:"foreignKey:ItemID"`
 	// Toppings []PizzaToppings  `json:"toppings" gorm:"foreignKey:PizzaID"`
 }
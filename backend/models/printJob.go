@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// PrintJob records a receipt sent to a thermal printer
+type PrintJob struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	InvoiceID uint      `json:"invoice_id" gorm:"not null;index"`
+	Printer   string    `json:"printer" gorm:"not null"` // Configured printer name
+	Target    string    `json:"target" gorm:"not null"`  // Address or path the job was written to
+	Columns   int       `json:"columns" gorm:"not null"`
+	Bytes     int       `json:"bytes" gorm:"not null"`
+	Status    string    `json:"status" gorm:"not null"` // sent, failed
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TaxRate is a named tax or charge (VAT, service charge, ...). Inclusive rates
+// are already contained in menu prices, exclusive ones are added on top.
+type TaxRate struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Code      string         `json:"code" gorm:"not null;uniqueIndex"`
+	Name      string         `json:"name" gorm:"not null"`
+	Rate      float64        `json:"rate" gorm:"not null"` // Percentage, e.g. 18 for 18%
+	Inclusive bool           `json:"inclusive" gorm:"default:false"`
+	IsActive  bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// TaxClass groups the tax rates charged on a kind of item. An item uses its own
+// TaxClassID, else the class for its item type, else the default class.
+type TaxClass struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Code      string         `json:"code" gorm:"not null;uniqueIndex"`
+	Name      string         `json:"name" gorm:"not null"`
+	ItemType  string         `json:"item_type" gorm:"index"` // pizza, beverage, topping, ... or empty
+	IsDefault bool           `json:"is_default" gorm:"default:false"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	TaxRates []TaxRate `json:"tax_rates" gorm:"many2many:tax_class_rates"`
+}
+
+// InvoiceTax is the tax charged for one rate on an invoice
+type InvoiceTax struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	InvoiceID     uint      `json:"invoice_id" gorm:"not null;index"`
+	TaxRateID     uint      `json:"tax_rate_id" gorm:"not null"`
+	Code          string    `json:"code" gorm:"not null"`
+	Name          string    `json:"name" gorm:"not null"`
+	Rate          float64   `json:"rate" gorm:"not null"`
+	Inclusive     bool      `json:"inclusive"`
+	TaxableAmount Money     `json:"taxable_amount" gorm:"not null"`
+	TaxAmount     Money     `json:"tax_amount" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -9,14 +9,16 @@ import (
 // Topping represents available toppings
 type Topping struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
+	ItemID    *uint          `json:"item_id" gorm:"index"` // Parent topping item; without one the topping is only a pizza modifier
 	ToppingID uint           `json:"topping_id" gorm:"not null"`
 	Name      string         `json:"name" gorm:"not null"`
-	Price     float64        `json:"price" gorm:"not null"`
+	Price     Money          `json:"price" gorm:"not null"`
 	IsActive  bool           `json:"is_active" gorm:"default:true"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	// Relationships
+	Item *Item `json:"item,omitempty" gorm:"foreignKey:ItemID"`
 	// Pizzas []PizzaToppings `json:"pizzas" gorm:"foreignKey:ToppingID"`
 }
@@ -0,0 +1,92 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ESC/POS control sequences used by the thermal receipt
+var (
+	escInit        = []byte{0x1b, 0x40}          // ESC @: reset the printer
+	escCodePage    = []byte{0x1b, 0x74, 16}      // ESC t 16: WPC1252 code page
+	escBoldOn      = []byte{0x1b, 0x45, 1}       // ESC E 1
+	escBoldOff     = []byte{0x1b, 0x45, 0}       // ESC E 0
+	escAlignLeft   = []byte{0x1b, 0x61, 0}       // ESC a 0
+	escAlignCenter = []byte{0x1b, 0x61, 1}       // ESC a 1
+	escFeedLines   = []byte{0x1b, 0x64, 4}       // ESC d 4: feed past the cutter
+	gsPartialCut   = []byte{0x1d, 0x56, 0x42, 0} // GS V 66 0: feed and partial cut
+)
+
+// ESCPOSOptions controls the thermal receipt layout
+type ESCPOSOptions struct {
+	Columns int  // Characters per line: 42 or 48 on 80mm paper
+	QRCode  bool // Print the invoice number as a QR code under the totals
+}
+
+// RenderESCPOS lays the receipt out for an ESC/POS thermal printer and returns
+// the raw byte stream, ending with a paper cut
+func RenderESCPOS(r Receipt, opts ESCPOSOptions) ([]byte, error) {
+	if opts.Columns != 42 && opts.Columns != 48 {
+		return nil, fmt.Errorf("unsupported column width %d, must be 42 or 48", opts.Columns)
+	}
+
+	var out bytes.Buffer
+	out.Write(escInit)
+	out.Write(escCodePage)
+
+	for _, line := range Layout(r, opts.Columns) {
+		if line.Bold {
+			out.Write(escBoldOn)
+		}
+		out.Write(encodeCP1252(line.Text))
+		if line.Bold {
+			out.Write(escBoldOff)
+		}
+		out.WriteByte('\n')
+	}
+
+	if opts.QRCode && r.InvoiceNumber != "" {
+		out.WriteByte('\n')
+		out.Write(escAlignCenter)
+		out.Write(qrCode(r.InvoiceNumber))
+		out.WriteByte('\n')
+		out.Write(escAlignLeft)
+	}
+
+	out.Write(escFeedLines)
+	out.Write(gsPartialCut)
+	return out.Bytes(), nil
+}
+
+// qrCode stores and prints data with the printer's built-in QR generator (GS ( k)
+func qrCode(data string) []byte {
+	var out bytes.Buffer
+	out.Write([]byte{0x1d, 0x28, 0x6b, 4, 0, 0x31, 0x41, 0x32, 0}) // model 2
+	out.Write([]byte{0x1d, 0x28, 0x6b, 3, 0, 0x31, 0x43, 6})       // module size 6 dots
+	out.Write([]byte{0x1d, 0x28, 0x6b, 3, 0, 0x31, 0x45, 0x31})    // error correction level M
+
+	payload := []byte(data)
+	size := len(payload) + 3
+	out.Write([]byte{0x1d, 0x28, 0x6b, byte(size % 256), byte(size / 256), 0x31, 0x50, 0x30})
+	out.Write(payload)
+
+	out.Write([]byte{0x1d, 0x28, 0x6b, 3, 0, 0x31, 0x51, 0x30}) // print the stored symbol
+	return out.Bytes()
+}
+
+// encodeCP1252 converts text for the WPC1252 code page, replacing anything it
+// cannot represent with '?'
+func encodeCP1252(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\n' || r == '\r':
+			out = append(out, ' ')
+		case r >= 32 && r < 127, r >= 160 && r <= 255:
+			out = append(out, byte(r))
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
@@ -0,0 +1,187 @@
+package receipt
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReceipt() Receipt {
+	return Receipt{
+		Config: Config{
+			ShopName: "Pizza Palace",
+			Address:  "12 Galle Road, Colombo 03",
+			Footer:   "Thank you for your order!",
+			Currency: "Rs.",
+		},
+		InvoiceNumber: "INV-2026-000042",
+		InvoiceDate:   time.Date(2026, 3, 14, 18, 30, 0, 0, time.UTC),
+		OrderID:       42,
+		Lines: []Line{
+			{Description: "Large Margherita with a very long description that needs wrapping", Quantity: 2, UnitPrice: 125000, Total: 250000},
+		},
+		Subtotal: 250000,
+		Tax:      25000,
+		Total:    275000,
+	}
+}
+
+func TestWrap(t *testing.T) {
+	text := "Large Margherita with extra cheese, olives, jalapenos and a thin crust"
+	for _, columns := range []int{42, 48} {
+		lines := wrap(text, columns)
+		for _, line := range lines {
+			if len([]rune(line)) > columns {
+				t.Errorf("%d columns: line %q is too wide", columns, line)
+			}
+		}
+		if got := strings.Join(lines, " "); got != text {
+			t.Errorf("%d columns: words lost, got %q", columns, got)
+		}
+	}
+
+	// Words wider than a line are split
+	long := strings.Repeat("x", 100)
+	lines := wrap(long, 42)
+	if len(lines) != 3 || lines[0] != long[:42] || lines[2] != long[84:] {
+		t.Errorf("long word wrapped as %q", lines)
+	}
+}
+
+func TestColumnsLR(t *testing.T) {
+	for _, columns := range []int{42, 48} {
+		line := columnsLR("  2 x 1,250.00", "2,500.00", columns)
+		if len(line) != columns {
+			t.Errorf("%d columns: line %q is %d wide", columns, line, len(line))
+		}
+		if !strings.HasPrefix(line, "  2 x 1,250.00 ") || !strings.HasSuffix(line, " 2,500.00") {
+			t.Errorf("%d columns: got %q", columns, line)
+		}
+
+		// The left side gives way to the amount
+		line = columnsLR(strings.Repeat("Pizza ", 10), "2,500.00", columns)
+		if len(line) != columns || !strings.HasSuffix(line, " 2,500.00") {
+			t.Errorf("%d columns: long left side gives %q", columns, line)
+		}
+	}
+}
+
+func TestRenderESCPOS(t *testing.T) {
+	for _, columns := range []int{42, 48} {
+		data, err := RenderESCPOS(testReceipt(), ESCPOSOptions{Columns: columns})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, append(append([]byte{}, escInit...), escCodePage...)) {
+			t.Errorf("%d columns: stream does not start with a reset and code page", columns)
+		}
+		if !bytes.HasSuffix(data, append(append([]byte{}, escFeedLines...), gsPartialCut...)) {
+			t.Errorf("%d columns: stream does not end with a feed and cut", columns)
+		}
+
+		// The shop name and the total are bold
+		for _, text := range []string{"PIZZA PALACE", "TOTAL"} {
+			i := bytes.Index(data, []byte(text))
+			if i < 0 {
+				t.Fatalf("%d columns: %q missing", columns, text)
+			}
+			on := bytes.LastIndex(data[:i], escBoldOn)
+			off := bytes.LastIndex(data[:i], escBoldOff)
+			if on < 0 || on < off {
+				t.Errorf("%d columns: %q is not bold", columns, text)
+			}
+		}
+
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			line = bytes.ReplaceAll(line, escBoldOn, nil)
+			line = bytes.ReplaceAll(line, escBoldOff, nil)
+			line = bytes.TrimPrefix(line, append(append([]byte{}, escInit...), escCodePage...))
+			if len(line) > columns {
+				t.Errorf("%d columns: line %q is %d wide", columns, line, len(line))
+			}
+		}
+		if bytes.Contains(data, []byte{0x1d, 0x28, 0x6b}) {
+			t.Errorf("%d columns: QR code printed without being asked for", columns)
+		}
+	}
+
+	if _, err := RenderESCPOS(testReceipt(), ESCPOSOptions{Columns: 40}); err == nil {
+		t.Error("40 columns accepted")
+	}
+}
+
+func TestRenderESCPOSQRCode(t *testing.T) {
+	data, err := RenderESCPOS(testReceipt(), ESCPOSOptions{Columns: 48, QRCode: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stored with GS ( k 31 50 30, sized as the payload plus 3, then printed
+	number := "INV-2026-000042"
+	store := append([]byte{0x1d, 0x28, 0x6b, byte(len(number) + 3), 0, 0x31, 0x50, 0x30}, number...)
+	printSymbol := []byte{0x1d, 0x28, 0x6b, 3, 0, 0x31, 0x51, 0x30}
+	i := bytes.Index(data, store)
+	if i < 0 {
+		t.Fatal("QR code data not stored")
+	}
+	if j := bytes.Index(data, printSymbol); j < i {
+		t.Error("QR code not printed after storing it")
+	}
+	if k := bytes.LastIndex(data[:i], escAlignCenter); k < 0 {
+		t.Error("QR code not centred")
+	}
+}
+
+func TestEncodeCP1252(t *testing.T) {
+	got := encodeCP1252("Café\n€5 ✓")
+	want := []byte{'C', 'a', 'f', 0xe9, ' ', '?', '5', ' ', '?'}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got % x, want % x", got, want)
+	}
+}
+
+func TestSendTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+
+	data, err := RenderESCPOS(testReceipt(), ESCPOSOptions{Columns: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Send("tcp://"+listener.Addr().String(), data, 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-received:
+		if !bytes.Equal(got, data) {
+			t.Errorf("printer received %d bytes, want %d", len(got), len(data))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("printer received nothing")
+	}
+
+	// Nothing listens on a closed port
+	listener.Close()
+	if err := Send(listener.Addr().String(), data, time.Second); err == nil {
+		t.Error("send to a closed port succeeded")
+	}
+}
@@ -0,0 +1,90 @@
+package receipt
+
+import (
+	"html/template"
+	"io"
+	"path/filepath"
+)
+
+const defaultHTMLTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.ShopName}} - {{.InvoiceNumber}}</title>
+<style>
+  body { font-family: "Courier New", monospace; font-size: 13px; margin: 0; background: #f4f4f4; }
+  .receipt { width: 80mm; margin: 16px auto; padding: 12px; background: #fff; }
+  .center { text-align: center; }
+  h1 { font-size: 18px; margin: 0 0 4px; }
+  table { width: 100%; border-collapse: collapse; }
+  td { padding: 2px 0; vertical-align: top; }
+  td.amount { text-align: right; white-space: nowrap; }
+  .modifier td { font-size: 11px; padding-left: 10px; }
+  .rule { border-top: 1px dashed #000; margin: 8px 0; }
+  .total td { font-weight: bold; font-size: 15px; }
+  @media print { body { background: #fff; } .receipt { margin: 0; } }
+</style>
+</head>
+<body>
+<div class="receipt">
+  <div class="center">
+    <h1>{{.ShopName}}</h1>
+    {{with .Address}}<div>{{.}}</div>{{end}}
+    {{with .Phone}}<div>Tel: {{.}}</div>{{end}}
+    {{range .HeaderLines}}<div>{{.}}</div>{{end}}
+  </div>
+  <div class="rule"></div>
+  <table>
+    <tr><td>Invoice</td><td class="amount">{{.InvoiceNumber}}</td></tr>
+    <tr><td>Date</td><td class="amount">{{.InvoiceDate.Format "2006-01-02 15:04"}}</td></tr>
+    <tr><td>Order</td><td class="amount">#{{.OrderID}}</td></tr>
+    {{with .CustomerName}}<tr><td>Customer</td><td class="amount">{{.}}</td></tr>{{end}}
+    {{with .CustomerTelNo}}<tr><td>Tel</td><td class="amount">{{.}}</td></tr>{{end}}
+    {{with .Fulfilment}}<tr><td>Type</td><td class="amount">{{.}}</td></tr>{{end}}
+    {{with .DeliverTo}}<tr><td colspan="2"><strong>Deliver to:</strong> {{.}}</td></tr>{{end}}
+    {{with .DeliveryNotes}}<tr><td colspan="2">{{.}}</td></tr>{{end}}
+  </table>
+  <div class="rule"></div>
+  <table>
+    {{range .Lines}}
+    <tr><td colspan="2">{{.Description}}</td></tr>
+    <tr><td>&nbsp;&nbsp;{{.Quantity}} x {{amount .UnitPrice}}</td><td class="amount">{{amount .Total}}</td></tr>
+    {{range .Modifiers}}<tr class="modifier"><td>{{.Description}}</td><td class="amount">{{if .Total}}{{amount .Total}}{{end}}</td></tr>{{end}}
+    {{end}}
+  </table>
+  <div class="rule"></div>
+  <table>
+    <tr><td>Subtotal</td><td class="amount">{{amount .Subtotal}}</td></tr>
+    {{range .Taxes}}<tr><td>{{.Label}}</td><td class="amount">{{amount .Amount}}</td></tr>{{else}}<tr><td>Tax</td><td class="amount">{{amount .Tax}}</td></tr>{{end}}
+    <tr class="total"><td>Total</td><td class="amount">{{.Money .Total}}</td></tr>
+    {{range .Payments}}<tr><td>{{.Label}}</td><td class="amount">{{amount .Amount}}</td></tr>{{end}}
+    {{if .Change}}<tr><td>Change</td><td class="amount">{{amount .Change}}</td></tr>{{end}}
+    {{with .PaymentStatus}}<tr><td>Payment</td><td class="amount">{{.}}</td></tr>{{end}}
+  </table>
+  {{with .Notes}}<div class="rule"></div><div>{{.}}</div>{{end}}
+  {{with .FooterLines}}<div class="rule"></div><div class="center">{{range .}}<div>{{.}}</div>{{end}}</div>{{end}}
+</div>
+</body>
+</html>
+`
+
+var templateFuncs = template.FuncMap{
+	"amount": FormatAmount,
+}
+
+// RenderHTML writes the receipt as a printable HTML page, using the template
+// at Config.TemplatePath when one is configured
+func RenderHTML(w io.Writer, r Receipt) error {
+	var tmpl *template.Template
+	var err error
+	if r.TemplatePath != "" {
+		tmpl, err = template.New(filepath.Base(r.TemplatePath)).Funcs(templateFuncs).ParseFiles(r.TemplatePath)
+	} else {
+		tmpl, err = template.New("receipt").Funcs(templateFuncs).Parse(defaultHTMLTemplate)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, r)
+}
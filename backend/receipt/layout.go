@@ -0,0 +1,168 @@
+package receipt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TextLine is one fixed-width line of a text receipt. Text is already padded
+// or aligned to the column width, so printers only need to handle emphasis.
+type TextLine struct {
+	Text string
+	Bold bool
+}
+
+// Layout lays a receipt out as fixed-width text for monospace output such as
+// the PDF receipt and thermal printers (42 or 48 columns on 80mm paper)
+func Layout(r Receipt, columns int) []TextLine {
+	var out []TextLine
+	add := func(text string, bold bool) {
+		out = append(out, TextLine{Text: text, Bold: bold})
+	}
+	rule := strings.Repeat("-", columns)
+
+	for _, line := range wrap(strings.ToUpper(r.ShopName), columns) {
+		add(center(line, columns), true)
+	}
+	for _, value := range []string{r.Address, r.Phone} {
+		for _, line := range wrap(value, columns) {
+			add(center(line, columns), false)
+		}
+	}
+	for _, header := range r.HeaderLines() {
+		for _, line := range wrap(header, columns) {
+			add(center(line, columns), false)
+		}
+	}
+	add(rule, false)
+
+	add(columnsLR("Invoice:", r.InvoiceNumber, columns), false)
+	add(columnsLR("Date:", r.InvoiceDate.Format("2006-01-02 15:04"), columns), false)
+	add(columnsLR("Order:", fmt.Sprintf("#%d", r.OrderID), columns), false)
+	if r.CustomerName != "" {
+		add(columnsLR("Customer:", r.CustomerName, columns), false)
+	}
+	if r.CustomerTelNo != "" {
+		add(columnsLR("Tel:", r.CustomerTelNo, columns), false)
+	}
+	if r.Fulfilment != "" {
+		add(columnsLR("Type:", r.Fulfilment, columns), false)
+	}
+	if r.DeliverTo != "" {
+		add("Deliver to:", true)
+		for _, line := range wrap(r.DeliverTo, columns) {
+			add(line, false)
+		}
+		for _, line := range wrap(r.DeliveryNotes, columns) {
+			add(line, false)
+		}
+	}
+	add(rule, false)
+
+	for _, item := range r.Lines {
+		for _, line := range wrap(item.Description, columns) {
+			add(line, false)
+		}
+		qty := fmt.Sprintf("  %d x %s", item.Quantity, FormatAmount(item.UnitPrice))
+		add(columnsLR(qty, FormatAmount(item.Total), columns), false)
+		for _, modifier := range item.Modifiers {
+			amount := ""
+			if modifier.Total != 0 {
+				amount = FormatAmount(modifier.Total)
+			}
+			add(columnsLR("  "+modifier.Description, amount, columns), false)
+		}
+	}
+	add(rule, false)
+
+	add(columnsLR("Subtotal", FormatAmount(r.Subtotal), columns), false)
+	if len(r.Taxes) == 0 {
+		add(columnsLR("Tax", FormatAmount(r.Tax), columns), false)
+	}
+	for _, tax := range r.Taxes {
+		add(columnsLR(tax.Label, FormatAmount(tax.Amount), columns), false)
+	}
+	add(columnsLR("TOTAL", r.Money(r.Total), columns), true)
+	for _, payment := range r.Payments {
+		add(columnsLR(payment.Label, FormatAmount(payment.Amount), columns), false)
+	}
+	if r.Change != 0 {
+		add(columnsLR("Change", FormatAmount(r.Change), columns), false)
+	}
+	if r.PaymentStatus != "" {
+		add(columnsLR("Payment:", strings.ToUpper(r.PaymentStatus), columns), false)
+	}
+	if r.Notes != "" {
+		add(rule, false)
+		for _, line := range wrap(r.Notes, columns) {
+			add(line, false)
+		}
+	}
+
+	if footer := r.FooterLines(); len(footer) > 0 {
+		add(rule, false)
+		for _, value := range footer {
+			for _, line := range wrap(value, columns) {
+				add(center(line, columns), false)
+			}
+		}
+	}
+
+	return out
+}
+
+// columnsLR puts left and right on one line, truncating left when both do not fit
+func columnsLR(left, right string, columns int) string {
+	space := columns - len([]rune(right)) - 1
+	if space < 0 {
+		return truncate(right, columns)
+	}
+	left = truncate(left, space)
+	return left + strings.Repeat(" ", columns-len([]rune(left))-len([]rune(right))) + right
+}
+
+func center(text string, columns int) string {
+	pad := (columns - len([]rune(text))) / 2
+	if pad <= 0 {
+		return text
+	}
+	return strings.Repeat(" ", pad) + text
+}
+
+func truncate(text string, columns int) string {
+	runes := []rune(text)
+	if len(runes) <= columns {
+		return text
+	}
+	return string(runes[:columns])
+}
+
+// wrap breaks text on word boundaries so no line is wider than columns
+func wrap(text string, columns int) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(text) {
+		for len([]rune(word)) > columns {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:columns]))
+			word = string(runes[columns:])
+		}
+		switch {
+		case current == "":
+			current = word
+		case len([]rune(current))+1+len([]rune(word)) <= columns:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
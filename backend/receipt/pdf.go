@@ -0,0 +1,119 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The PDF receipt is a narrow 80mm page set in Courier, so the fixed-width
+// Layout can be placed line by line without any font metrics.
+const (
+	pdfColumns         = 42
+	pdfFontSize        = 8.0
+	pdfLeading         = 10.0
+	pdfMargin          = 14.0
+	pdfCharWidth       = pdfFontSize * 0.6 // Courier glyphs are 600/1000 em wide
+	pdfMaxLinesPerPage = 150
+)
+
+// RenderPDF writes the receipt as a self-contained PDF document. It only uses
+// the standard Courier fonts, so no font files are embedded.
+func RenderPDF(w io.Writer, r Receipt) error {
+	lines := Layout(r, pdfColumns)
+
+	var pages [][]TextLine
+	for len(lines) > pdfMaxLinesPerPage {
+		pages = append(pages, lines[:pdfMaxLinesPerPage])
+		lines = lines[pdfMaxLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	pdf := &pdfDocument{}
+	pdf.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are fixed; each page adds a page object and its content stream
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	pdf.object("<< /Type /Catalog /Pages 2 0 R >>")
+	pdf.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	pdf.object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	pdf.object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+
+	width := 2*pdfMargin + pdfColumns*pdfCharWidth
+	for i, page := range pages {
+		height := 2*pdfMargin + float64(len(page))*pdfLeading
+		pdf.object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			width, height, 6+2*i))
+
+		content := pageContent(page, height)
+		pdf.object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	pdf.finish()
+	_, err := w.Write(pdf.buf.Bytes())
+	return err
+}
+
+func pageContent(lines []TextLine, height float64) string {
+	var content strings.Builder
+	content.WriteString("BT\n")
+	bold := false
+	fmt.Fprintf(&content, "/F1 %.1f Tf\n", pdfFontSize)
+	for i, line := range lines {
+		if line.Bold != bold {
+			bold = line.Bold
+			font := "F1"
+			if bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&content, "/%s %.1f Tf\n", font, pdfFontSize)
+		}
+		y := height - pdfMargin - pdfFontSize - float64(i)*pdfLeading
+		fmt.Fprintf(&content, "1 0 0 1 %.2f %.2f Tm (%s) Tj\n", pdfMargin, y, pdfString(line.Text))
+	}
+	content.WriteString("ET")
+	return content.String()
+}
+
+// pdfString escapes text for a PDF literal string in WinAnsi encoding
+func pdfString(text string) string {
+	var out strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r >= 32 && r < 127:
+			out.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&out, "\\%03o", r)
+		default:
+			out.WriteByte('?')
+		}
+	}
+	return out.String()
+}
+
+type pdfDocument struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (d *pdfDocument) object(body string) {
+	d.offsets = append(d.offsets, d.buf.Len())
+	fmt.Fprintf(&d.buf, "%d 0 obj\n%s\nendobj\n", len(d.offsets), body)
+}
+
+func (d *pdfDocument) finish() {
+	xref := d.buf.Len()
+	fmt.Fprintf(&d.buf, "xref\n0 %d\n0000000000 65535 f \n", len(d.offsets)+1)
+	for _, offset := range d.offsets {
+		fmt.Fprintf(&d.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&d.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.offsets)+1, xref)
+}
@@ -0,0 +1,77 @@
+package receipt
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Send writes a print job to a printer target. Targets are either network
+// printers ("tcp://192.168.1.50:9100" or just "host:port", usually port 9100)
+// or a file or device path ("file:///tmp/receipt.bin", "/dev/usb/lp0").
+func Send(target string, data []byte, timeout time.Duration) error {
+	switch {
+	case strings.HasPrefix(target, "file://"):
+		return writeFile(strings.TrimPrefix(target, "file://"), data)
+	case strings.HasPrefix(target, "/"):
+		return writeFile(target, data)
+	case strings.HasPrefix(target, "tcp://"):
+		return writeTCP(strings.TrimPrefix(target, "tcp://"), data, timeout)
+	case strings.Contains(target, ":"):
+		return writeTCP(target, data, timeout)
+	}
+	return fmt.Errorf("unsupported printer target %q", target)
+}
+
+func writeTCP(address string, data []byte, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	_, err = conn.Write(data)
+	return err
+}
+
+func writeFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// PrintersFromEnv returns the configured printer targets by name.
+// PRINTER_ADDRESS is the "default" printer; PRINTERS adds named ones as
+// "counter=tcp://192.168.1.50:9100,kitchen=/dev/usb/lp0".
+func PrintersFromEnv() map[string]string {
+	printers := map[string]string{}
+	if address := strings.TrimSpace(os.Getenv("PRINTER_ADDRESS")); address != "" {
+		printers["default"] = address
+	}
+	for _, entry := range strings.Split(os.Getenv("PRINTERS"), ",") {
+		name, target, ok := strings.Cut(entry, "=")
+		if ok && strings.TrimSpace(name) != "" && strings.TrimSpace(target) != "" {
+			printers[strings.TrimSpace(name)] = strings.TrimSpace(target)
+		}
+	}
+	return printers
+}
+
+// ColumnsFromEnv returns the thermal printer line width from PRINTER_COLUMNS, 48 by default
+func ColumnsFromEnv() int {
+	if os.Getenv("PRINTER_COLUMNS") == "42" {
+		return 42
+	}
+	return 48
+}
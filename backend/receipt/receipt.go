@@ -0,0 +1,141 @@
+// Package receipt renders invoices for customers: HTML pages, PDF documents
+// and, for the counter printers, ESC/POS byte streams.
+package receipt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"main/models"
+)
+
+// Config holds the shop branding printed on every receipt
+type Config struct {
+	ShopName     string
+	Address      string
+	Phone        string
+	Header       string // Extra lines under the shop details, separated by "|" or newlines
+	Footer       string // Closing lines, separated by "|" or newlines
+	Currency     string
+	TemplatePath string // Optional html/template file replacing the built-in HTML layout
+}
+
+// ConfigFromEnv reads the receipt branding from the environment
+func ConfigFromEnv() Config {
+	cfg := Config{
+		ShopName:     os.Getenv("SHOP_NAME"),
+		Address:      os.Getenv("SHOP_ADDRESS"),
+		Phone:        os.Getenv("SHOP_PHONE"),
+		Header:       os.Getenv("RECEIPT_HEADER"),
+		Footer:       os.Getenv("RECEIPT_FOOTER"),
+		Currency:     os.Getenv("CURRENCY_SYMBOL"),
+		TemplatePath: os.Getenv("RECEIPT_HTML_TEMPLATE"),
+	}
+	if cfg.ShopName == "" {
+		cfg.ShopName = "Pizza Palace"
+	}
+	if cfg.Footer == "" {
+		cfg.Footer = "Thank you for your order!"
+	}
+	if cfg.Currency == "" {
+		cfg.Currency = "Rs."
+	}
+	return cfg
+}
+
+// HeaderLines splits the configured header into printable lines
+func (c Config) HeaderLines() []string {
+	return splitLines(c.Header)
+}
+
+// FooterLines splits the configured footer into printable lines
+func (c Config) FooterLines() []string {
+	return splitLines(c.Footer)
+}
+
+// Receipt is everything printed for one invoice
+type Receipt struct {
+	Config
+
+	InvoiceNumber string
+	InvoiceDate   time.Time
+	OrderID       uint
+	CustomerName  string
+	CustomerTelNo string
+	Fulfilment    string // e.g. "Dine in, table 4" or "Takeaway, pickup 18:30"
+	DeliverTo     string // Delivery address, empty for orders that are not delivered
+	DeliveryNotes string
+	Lines         []Line
+	Subtotal      models.Money
+	Tax           models.Money
+	Taxes         []TaxLine // Per-rate breakdown of Tax, if known
+	Total         models.Money
+	Payments      []PaymentLine // Payments received, cash rounding included
+	Change        models.Money  // Cash given back
+	PaymentStatus string
+	Notes         string
+}
+
+// Line is one order line with its topping modifiers
+type Line struct {
+	Description string
+	Quantity    int
+	UnitPrice   models.Money
+	Total       models.Money
+	Modifiers   []Modifier
+}
+
+// TaxLine is the amount charged for one tax rate, e.g. "VAT 18%"
+type TaxLine struct {
+	Label  string
+	Amount models.Money
+}
+
+// PaymentLine is one payment printed under the total, e.g. "Cash" or "Rounding"
+type PaymentLine struct {
+	Label  string
+	Amount models.Money
+}
+
+// Modifier is a topping added to or removed from a line
+type Modifier struct {
+	Description string
+	Total       models.Money
+}
+
+// Money formats an amount with the configured currency, e.g. "Rs. 1,250.00"
+func (r Receipt) Money(amount models.Money) string {
+	return r.Currency + " " + FormatAmount(amount)
+}
+
+// FormatAmount formats an amount with two decimals and thousands separators
+func FormatAmount(amount models.Money) string {
+	sign := ""
+	cents := int64(amount)
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	whole := fmt.Sprintf("%d", cents/100)
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return fmt.Sprintf("%s%s.%02d", sign, grouped.String(), cents%100)
+}
+
+func splitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == '\n' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
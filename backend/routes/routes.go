@@ -16,18 +16,32 @@ func SetupRoutes() *mux.Router {
 	// Customer routes
 	api.HandleFunc("/customers", controllers.GetCustomers).Methods("GET")
 	api.HandleFunc("/customers/telno/{telno}", controllers.GetCustomerByTelNo).Methods("GET")
+	api.HandleFunc("/customers/deleted", controllers.GetDeletedCustomers).Methods("GET")
+	api.HandleFunc("/customers/duplicates", controllers.GetCustomerDuplicates).Methods("GET")
 	api.HandleFunc("/customers/{id:[0-9]+}", controllers.GetCustomerByID).Methods("GET")
 	api.HandleFunc("/customers", controllers.CreateCustomer).Methods("POST")
 	api.HandleFunc("/customers/{id:[0-9]+}", controllers.UpdateCustomer).Methods("PUT")
 	api.HandleFunc("/customers/{id:[0-9]+}", controllers.DeleteCustomer).Methods("DELETE")
+	api.HandleFunc("/customers/{id:[0-9]+}/restore", controllers.RestoreCustomer).Methods("POST")
+	api.HandleFunc("/customers/{id:[0-9]+}/orders", controllers.GetCustomerOrders).Methods("GET")
+	api.HandleFunc("/customers/{id:[0-9]+}/summary", controllers.GetCustomerSummary).Methods("GET")
+	api.HandleFunc("/customers/{id:[0-9]+}/repeat-last-order", controllers.RepeatLastOrder).Methods("POST")
+	api.HandleFunc("/customers/{id:[0-9]+}/addresses", controllers.GetCustomerAddresses).Methods("GET")
+	api.HandleFunc("/customers/{id:[0-9]+}/addresses", controllers.CreateCustomerAddress).Methods("POST")
+	api.HandleFunc("/customers/{id:[0-9]+}/addresses/{addressId:[0-9]+}", controllers.UpdateCustomerAddress).Methods("PUT")
+	api.HandleFunc("/customers/{id:[0-9]+}/addresses/{addressId:[0-9]+}", controllers.DeleteCustomerAddress).Methods("DELETE")
+	api.HandleFunc("/customers/{id:[0-9]+}/addresses/{addressId:[0-9]+}/default", controllers.SetDefaultCustomerAddress).Methods("POST")
 
 	// Item management routes
 	api.HandleFunc("/items", controllers.GetItems).Methods("GET")
 	api.HandleFunc("/items/{id:[0-9]+}", controllers.GetItemByID).Methods("GET")
 	api.HandleFunc("/items/type/{type}", controllers.GetItemsByType).Methods("GET")
+	api.HandleFunc("/items/{id:[0-9]+}/variants", controllers.GetItemVariants).Methods("GET")
+	api.HandleFunc("/catalog", controllers.GetCatalog).Methods("GET")
 	api.HandleFunc("/items", controllers.CreateItem).Methods("POST")
 	api.HandleFunc("/items/{id:[0-9]+}", controllers.UpdateItem).Methods("PUT")
 	api.HandleFunc("/items/{id:[0-9]+}", controllers.DeleteItem).Methods("DELETE")
+	api.HandleFunc("/items/{id:[0-9]+}/restore", controllers.RestoreItem).Methods("POST")
 
 	// // Pizza routes
 	api.HandleFunc("/pizzas", controllers.GetPizzas).Methods("GET")
@@ -35,6 +49,7 @@ func SetupRoutes() *mux.Router {
 	api.HandleFunc("/pizzas", controllers.CreatePizza).Methods("POST")
 	api.HandleFunc("/pizzas/{id:[0-9]+}", controllers.UpdatePizza).Methods("PUT")
 	api.HandleFunc("/pizzas/{id:[0-9]+}", controllers.DeletePizza).Methods("DELETE")
+	api.HandleFunc("/pizzas/{id:[0-9]+}/restore", controllers.RestorePizza).Methods("POST")
 
 	// // Topping routes
 	api.HandleFunc("/toppings", controllers.GetToppings).Methods("GET")
@@ -42,6 +57,7 @@ func SetupRoutes() *mux.Router {
 	api.HandleFunc("/toppings", controllers.CreateTopping).Methods("POST")
 	api.HandleFunc("/toppings/{id:[0-9]+}", controllers.UpdateTopping).Methods("PUT")
 	api.HandleFunc("/toppings/{id:[0-9]+}", controllers.DeleteTopping).Methods("DELETE")
+	api.HandleFunc("/toppings/{id:[0-9]+}/restore", controllers.RestoreTopping).Methods("POST")
 
 	// Beverage routes
 	api.HandleFunc("/beverages", controllers.GetBeverages).Methods("GET")
@@ -49,12 +65,26 @@ func SetupRoutes() *mux.Router {
 	api.HandleFunc("/beverages", controllers.CreateBeverage).Methods("POST")
 	api.HandleFunc("/beverages/{id:[0-9]+}", controllers.UpdateBeverage).Methods("PUT")
 	api.HandleFunc("/beverages/{id:[0-9]+}", controllers.DeleteBeverage).Methods("DELETE")
+	api.HandleFunc("/beverages/{id:[0-9]+}/restore", controllers.RestoreBeverage).Methods("POST")
 
 	// // Order routes
 	api.HandleFunc("/orders", controllers.GetOrders).Methods("GET")
 	api.HandleFunc("/orders/{id:[0-9]+}", controllers.GetOrderByID).Methods("GET")
 	api.HandleFunc("/orders", controllers.CreateOrder).Methods("POST")
 	api.HandleFunc("/orders/{id:[0-9]+}/status", controllers.UpdateOrderStatus).Methods("PUT")
+	api.HandleFunc("/orders/{id:[0-9]+}/timeline", controllers.GetOrderTimeline).Methods("GET")
+	api.HandleFunc("/orders/{id:[0-9]+}/cancel", controllers.CancelOrder).Methods("POST")
+	api.HandleFunc("/orders/{id:[0-9]+}/items", controllers.AddOrderItem).Methods("POST")
+	api.HandleFunc("/orders/{id:[0-9]+}/items/{itemId:[0-9]+}", controllers.UpdateOrderItem).Methods("PUT")
+	api.HandleFunc("/orders/{id:[0-9]+}/items/{itemId:[0-9]+}", controllers.DeleteOrderItem).Methods("DELETE")
+
+	// Tax configuration routes
+	api.HandleFunc("/tax-rates", controllers.GetTaxRates).Methods("GET")
+	api.HandleFunc("/tax-rates", controllers.CreateTaxRate).Methods("POST")
+	api.HandleFunc("/tax-rates/{id:[0-9]+}", controllers.UpdateTaxRate).Methods("PUT")
+	api.HandleFunc("/tax-classes", controllers.GetTaxClasses).Methods("GET")
+	api.HandleFunc("/tax-classes", controllers.CreateTaxClass).Methods("POST")
+	api.HandleFunc("/tax-classes/{id:[0-9]+}", controllers.UpdateTaxClass).Methods("PUT")
 
 	// Invoice/Bill routes
 	api.HandleFunc("/invoices", controllers.GetInvoices).Methods("GET")
@@ -62,11 +92,49 @@ func SetupRoutes() *mux.Router {
 	api.HandleFunc("/invoices/order/{orderId:[0-9]+}", controllers.GetInvoiceByOrderID).Methods("GET")
 	api.HandleFunc("/invoices", controllers.CreateInvoice).Methods("POST")
 	api.HandleFunc("/invoices/{id:[0-9]+}/payment-status", controllers.UpdateInvoicePaymentStatus).Methods("PUT")
-	// api.HandleFunc("/invoices/{id:[0-9]+}/print", controllers.GetPrintableInvoice).Methods("GET")
+	api.HandleFunc("/invoices/{id:[0-9]+}/print", controllers.GetPrintableInvoice).Methods("GET")
+	api.HandleFunc("/invoices/{id:[0-9]+}/print-jobs", controllers.CreatePrintJob).Methods("POST")
+	api.HandleFunc("/invoices/{id:[0-9]+}/payments", controllers.GetInvoicePayments).Methods("GET")
+	api.HandleFunc("/invoices/{id:[0-9]+}/payments", controllers.RecordPayment).Methods("POST")
+	api.HandleFunc("/invoices/{id:[0-9]+}/cash-due", controllers.GetCashDue).Methods("GET")
+	api.HandleFunc("/payments/{id:[0-9]+}/reverse", controllers.ReversePayment).Methods("POST")
+	api.HandleFunc("/invoices/{id:[0-9]+}/credit-notes", controllers.GetInvoiceCreditNotes).Methods("GET")
+	api.HandleFunc("/invoices/{id:[0-9]+}/credit-notes", controllers.CreateCreditNote).Methods("POST")
+	api.HandleFunc("/credit-notes/{id:[0-9]+}", controllers.GetCreditNoteByID).Methods("GET")
+
+	// Delivery zone routes
+	api.HandleFunc("/delivery-zones", controllers.GetDeliveryZones).Methods("GET")
+	api.HandleFunc("/delivery-zones", controllers.CreateDeliveryZone).Methods("POST")
+	api.HandleFunc("/delivery-zones/check", controllers.CheckDeliveryZone).Methods("GET")
+	api.HandleFunc("/delivery-zones/{id:[0-9]+}", controllers.GetDeliveryZoneByID).Methods("GET")
+	api.HandleFunc("/delivery-zones/{id:[0-9]+}", controllers.UpdateDeliveryZone).Methods("PUT")
+	api.HandleFunc("/delivery-zones/{id:[0-9]+}", controllers.DeleteDeliveryZone).Methods("DELETE")
+
+	// Driver dispatch routes
+	api.HandleFunc("/drivers", controllers.GetDrivers).Methods("GET")
+	api.HandleFunc("/drivers", controllers.CreateDriver).Methods("POST")
+	api.HandleFunc("/drivers/{id:[0-9]+}", controllers.GetDriverByID).Methods("GET")
+	api.HandleFunc("/drivers/{id:[0-9]+}", controllers.UpdateDriver).Methods("PUT")
+	api.HandleFunc("/drivers/{id:[0-9]+}", controllers.DeleteDriver).Methods("DELETE")
+	api.HandleFunc("/drivers/{id:[0-9]+}/locations", controllers.RecordDriverLocation).Methods("POST")
+	api.HandleFunc("/drivers/{id:[0-9]+}/locations", controllers.GetDriverLocations).Methods("GET")
+	api.HandleFunc("/drivers/{id:[0-9]+}/settle", controllers.SettleDriver).Methods("POST")
+	api.HandleFunc("/deliveries", controllers.GetDeliveryAssignments).Methods("GET")
+	api.HandleFunc("/orders/{id:[0-9]+}/driver", controllers.AssignOrderDriver).Methods("POST")
+	api.HandleFunc("/orders/{id:[0-9]+}/tracking", controllers.GetOrderTracking).Methods("GET")
+
+	// Cash drawer routes
+	api.HandleFunc("/cash-sessions", controllers.GetCashSessions).Methods("GET")
+	api.HandleFunc("/cash-sessions", controllers.OpenCashSession).Methods("POST")
+	api.HandleFunc("/cash-sessions/{id:[0-9]+}", controllers.GetCashSessionByID).Methods("GET")
+	api.HandleFunc("/cash-sessions/{id:[0-9]+}/movements", controllers.AddCashMovement).Methods("POST")
+	api.HandleFunc("/cash-sessions/{id:[0-9]+}/close", controllers.CloseCashSession).Methods("POST")
+	api.HandleFunc("/cash-sessions/{id:[0-9]+}/z-report", controllers.GetZReport).Methods("GET")
 
-	// // Dashboard and Reports routes
-	// api.HandleFunc("/dashboard/stats", controllers.GetDashboardStats).Methods("GET")
-	// api.HandleFunc("/reports/sales", controllers.GetSalesReport).Methods("GET")
+	// Dashboard and Reports routes
+	api.HandleFunc("/dashboard/stats", controllers.GetDashboardStats).Methods("GET")
+	api.HandleFunc("/reports/sales", controllers.GetSalesReport).Methods("GET")
+	api.HandleFunc("/reports/tax", controllers.GetTaxReport).Methods("GET")
 
 	return r
 }
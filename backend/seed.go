@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log"
+
+	"main/models"
+)
+
+// seedDefaultTaxes creates a 10% VAT charged on top of every item on first
+// start, matching the rate that used to be hard-coded in CreateInvoice
+func seedDefaultTaxes() {
+	var count int64
+	if err := DB.Model(&models.TaxRate{}).Unscoped().Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+
+	class := models.TaxClass{
+		Code:      "STANDARD",
+		Name:      "Standard rated",
+		IsDefault: true,
+		TaxRates: []models.TaxRate{
+			{Code: "VAT", Name: "VAT", Rate: 10, IsActive: true},
+		},
+	}
+	if err := DB.Create(&class).Error; err != nil {
+		log.Printf("Failed to seed default tax rates: %v", err)
+		return
+	}
+	log.Println("Seeded default tax class STANDARD with 10% VAT")
+}
@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+)
+
+// SendCSVResponse writes rows as a downloadable CSV file
+func SendCSVResponse(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+	writer.WriteAll(rows)
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// phoneCountry is what is needed to turn a national number into E.164
+type phoneCountry struct {
+	CallingCode string
+	Length      int // Digits in a national number without the trunk prefix 0
+}
+
+var phoneCountries = map[string]phoneCountry{
+	"LK": {CallingCode: "94", Length: 9},
+	"IN": {CallingCode: "91", Length: 10},
+	"MV": {CallingCode: "960", Length: 7},
+	"GB": {CallingCode: "44", Length: 10},
+	"AU": {CallingCode: "61", Length: 9},
+	"AE": {CallingCode: "971", Length: 9},
+	"US": {CallingCode: "1", Length: 10},
+}
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// DefaultPhoneCountry returns PHONE_DEFAULT_COUNTRY, the ISO country code that
+// numbers without a country code belong to, LK unless configured
+func DefaultPhoneCountry() string {
+	country := strings.ToUpper(strings.TrimSpace(os.Getenv("PHONE_DEFAULT_COUNTRY")))
+	if _, ok := phoneCountries[country]; !ok {
+		return "LK"
+	}
+	return country
+}
+
+// NormalizePhone converts a phone number as typed ("077 123 4567",
+// "0771234567", "+94 77 123-4567", "0094771234567") to E.164 ("+94771234567").
+// Numbers without a country code are read as numbers of the default country.
+func NormalizePhone(raw string) (string, error) {
+	international := false
+	digits := make([]byte, 0, len(raw))
+	for i, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, byte(r))
+		case r == '+' && i == 0:
+			international = true
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+	number := string(digits)
+
+	country := phoneCountries[DefaultPhoneCountry()]
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case strings.HasPrefix(number, "0"):
+		number = country.CallingCode + number[1:]
+	case len(number) == country.Length:
+		number = country.CallingCode + number
+	}
+
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	// National numbers of the default country must have its length
+	if strings.HasPrefix(number, country.CallingCode) && len(number) != len(country.CallingCode)+country.Length {
+		return "", ErrInvalidPhone
+	}
+	return "+" + number, nil
+}
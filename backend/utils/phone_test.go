@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		country string
+		raw     string
+		want    string // "" when the number is invalid
+	}{
+		// Local numbers of the default country
+		{"LK", "0771234567", "+94771234567"},
+		{"LK", "771234567", "+94771234567"},
+		{"LK", "077 123 4567", "+94771234567"},
+		{"LK", "077-123-4567", "+94771234567"},
+		{"LK", "(077) 123.4567", "+94771234567"},
+		{"LK", "  0771234567  ", "+94771234567"},
+		{"IN", "98765 43210", "+919876543210"},
+		{"IN", "098765-43210", "+919876543210"},
+		{"", "0771234567", "+94771234567"},   // LK unless configured
+		{"XX", "0771234567", "+94771234567"}, // Unknown countries fall back to LK
+
+		// International numbers
+		{"LK", "+94771234567", "+94771234567"},
+		{"LK", "+94 77 123-4567", "+94771234567"},
+		{"LK", "0094771234567", "+94771234567"},
+		{"LK", "+44 20 7946 0958", "+442079460958"},
+		{"LK", "+1 (415) 555-2671", "+14155552671"},
+		{"IN", "+94771234567", "+94771234567"},
+
+		// Wrong lengths
+		{"LK", "077 123 456", ""},
+		{"LK", "07712345678", ""},
+		{"LK", "+94 77 123 456", ""}, // Default country numbers must have its length, even with +
+		{"LK", "12345", ""},
+		{"LK", "+1234567", ""},
+		{"LK", "+1234567890123456", ""},
+		{"IN", "0771234567", ""},
+
+		// Not a phone number
+		{"LK", "", ""},
+		{"LK", "077 123 4567 ext 2", ""},
+		{"LK", "077+1234567", ""},
+		{"LK", "+0771234567", ""},
+		{"LK", "+ 94771234567x", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.country+" "+tt.raw, func(t *testing.T) {
+			t.Setenv("PHONE_DEFAULT_COUNTRY", tt.country)
+			got, err := NormalizePhone(tt.raw)
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalidPhone) {
+					t.Errorf("NormalizePhone(%q) = %q, %v; want ErrInvalidPhone", tt.raw, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizePhone(%q) = %q, %v; want %q", tt.raw, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultPhoneCountry(t *testing.T) {
+	tests := map[string]string{
+		"":    "LK",
+		"in":  "IN",
+		" GB": "GB",
+		"ZZ":  "LK",
+	}
+	for value, want := range tests {
+		t.Setenv("PHONE_DEFAULT_COUNTRY", value)
+		if got := DefaultPhoneCountry(); got != want {
+			t.Errorf("PHONE_DEFAULT_COUNTRY=%q gives %q, want %q", value, got, want)
+		}
+	}
+}
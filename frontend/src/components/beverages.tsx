@@ -34,7 +34,8 @@ const BeveragesTable: React.FC<BeveragesTableProps> = ({ onNavigateToBilling })
         console.log('Fetched beverages:', jsonData);
 
         if (response.ok) {
-          setBeverages(jsonData.data);
+          // Prices arrive as decimal strings, e.g. "450.00"
+          setBeverages(jsonData.data.map((b: Beverage) => ({ ...b, price: Number(b.price) })));
         }
       } catch (error) {
         console.error('Failed to fetch beverages:', error);
@@ -26,7 +26,8 @@ const Pizza: React.FC<PizzaPageProps> = ({ onNavigateToBilling }) => {
       const response = await fetch('http://localhost:8080/api/pizzas');
       const jsonData = await response.json();
       if (response.ok) {
-        setPizzas(jsonData.data);
+        // Prices arrive as decimal strings, e.g. "1250.00"
+        setPizzas(jsonData.data.map((p: Pizza) => ({ ...p, price: Number(p.price) })));
       }
     };
     fetchPizzas();
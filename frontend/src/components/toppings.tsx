@@ -24,7 +24,8 @@ const ToppingsTable: React.FC<ToppingsTableProps> = ({ onNavigateToBilling }) =>
         console.log('Fetched toppings:', jsonData);
 
         if (response.ok) {
-          setToppings(jsonData.data);
+          // Prices arrive as decimal strings, e.g. "150.00"
+          setToppings(jsonData.data.map((t: Topping) => ({ ...t, price: Number(t.price) })));
         }
       } catch (error) {
         console.error('Failed to fetch toppings:', error);